@@ -3,7 +3,9 @@ module github.com/eOne007/final-project-yapr
 go 1.24.0
 
 require (
+	github.com/golang-jwt/jwt/v5 v5.3.1
 	github.com/jmoiron/sqlx v1.4.0
+	github.com/lib/pq v1.12.3
 	github.com/stretchr/testify v1.11.1
 	modernc.org/sqlite v1.45.0
 )
@@ -0,0 +1,194 @@
+package repeater
+
+import (
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Calendar - календарь рабочих дней, по которому считаются правила b и bm
+type Calendar interface {
+	// Workday проверяет, что дата - рабочий день
+	Workday(date time.Time) bool
+}
+
+// weekdays - календарь без праздников: рабочие дни с понедельника по пятницу
+type weekdays struct{}
+
+func (weekdays) Workday(date time.Time) bool {
+	return isoWeekday(date) <= 5
+}
+
+// Holidays - календарь с праздниками и переносами: ключ - дата в формате 20060102,
+// значение true - рабочий день (перенесенный выходной), false - нерабочий
+// Дни, которых нет в календаре, рабочие с понедельника по пятницу
+type Holidays map[string]bool
+
+func (h Holidays) Workday(date time.Time) bool {
+	if working, ok := h[date.Format("20060102")]; ok {
+		return working
+	}
+	return isoWeekday(date) <= 5
+}
+
+// Business - правило b <дни>: повторение через заданное число рабочих дней
+type Business struct {
+	Interval int      // от 1 до 400
+	Calendar Calendar // nil - рабочие дни с понедельника по пятницу
+}
+
+// BusinessMonthly - правило bm <рабочие дни> [месяцы]: повторение в N-й рабочий день месяца
+type BusinessMonthly struct {
+	Days     []int    // от 1 до 23 с начала месяца и от -1 до -23 с конца, по возрастанию
+	Months   []int    // от 1 до 12 по возрастанию, пустой список - каждый месяц
+	Calendar Calendar // nil - рабочие дни с понедельника по пятницу
+}
+
+// maxBusinessDays - наибольшее число рабочих дней в месяце без праздников
+const maxBusinessDays = 23
+
+// UseCalendar возвращает правило, которое считает рабочие дни по календарю cal
+// Правила, не связанные с рабочими днями, возвращаются без изменений
+func UseCalendar(rule Rule, cal Calendar) Rule {
+	switch r := rule.(type) {
+	case Business:
+		r.Calendar = cal
+		return r
+	case BusinessMonthly:
+		r.Calendar = cal
+		return r
+	}
+	return rule
+}
+
+// calendarOrDefault возвращает календарь правила или календарь без праздников
+func calendarOrDefault(cal Calendar) Calendar {
+	if cal == nil {
+		return weekdays{}
+	}
+	return cal
+}
+
+func (r Business) String() string {
+	return "b " + strconv.Itoa(r.Interval)
+}
+
+// Next возвращает Interval-й рабочий день после after
+// Если в календаре нет рабочих дней в течение года, дат по правилу больше нет
+func (r Business) Next(after time.Time) (time.Time, bool) {
+	cal := calendarOrDefault(r.Calendar)
+	date := day(after)
+	for n, idle := 0, 0; n < r.Interval; {
+		date = date.AddDate(0, 0, 1)
+		if !cal.Workday(date) {
+			if idle++; idle > 366 {
+				return time.Time{}, false
+			}
+			continue
+		}
+		n, idle = n+1, 0
+	}
+	return date, true
+}
+
+// after возвращает первую дату после now без перебора дней: рабочие дни от start до now
+// считаются по целым неделям с поправкой на праздники и переносы из календаря
+// Даты по правилу - каждый Interval-й рабочий день после start, поэтому следующая дата
+// отстоит от now на остаток до кратного Interval числа рабочих дней
+func (r Business) after(start, now time.Time) (time.Time, bool) {
+	start, now = day(start), day(now)
+	if !AfterNow(now, start) {
+		return r.Next(start)
+	}
+	passed := weekdaysBetween(start, now)
+	switch cal := calendarOrDefault(r.Calendar).(type) {
+	case weekdays:
+	case Holidays:
+		for key, working := range cal {
+			date, err := time.Parse("20060102", key)
+			if err != nil || !AfterNow(date, start) || AfterNow(date, now) || working == (isoWeekday(date) <= 5) {
+				continue
+			}
+			if working {
+				passed++
+			} else {
+				passed--
+			}
+		}
+	default:
+		// для произвольного календаря рабочие дни известны только по одному
+		date, ok := r.Next(start)
+		for ok && !AfterNow(date, now) {
+			date, ok = r.Next(date)
+		}
+		return date, ok
+	}
+	return Business{Interval: r.Interval - passed%r.Interval, Calendar: r.Calendar}.Next(now)
+}
+
+// weekdaysBetween возвращает количество дней с понедельника по пятницу после from до to включительно
+func weekdaysBetween(from, to time.Time) int {
+	days := daysBetween(from, to)
+	n := days / 7 * 5
+	for date := from.AddDate(0, 0, days/7*7+1); !AfterNow(date, to); date = date.AddDate(0, 0, 1) {
+		if isoWeekday(date) <= 5 {
+			n++
+		}
+	}
+	return n
+}
+
+func (r BusinessMonthly) String() string {
+	if len(r.Months) == 0 {
+		return "bm " + joinList(r.Days)
+	}
+	return "bm " + joinList(r.Days) + " " + joinList(r.Months)
+}
+
+// Next возвращает ближайший после after рабочий день месяца из Days в одном из месяцев Months
+func (r BusinessMonthly) Next(after time.Time) (time.Time, bool) {
+	cal := calendarOrDefault(r.Calendar)
+	year, month, d := after.Date()
+	for i := 0; i < monthsInLeapCycle; i++ {
+		if len(r.Months) == 0 || slices.Contains(r.Months, int(month)) {
+			if next, ok := r.dayInMonth(cal, year, month, d); ok {
+				return time.Date(year, month, next, 0, 0, 0, 0, time.UTC), true
+			}
+		}
+		month++
+		if month > time.December {
+			year, month = year+1, time.January
+		}
+		d = 0
+	}
+	return time.Time{}, false
+}
+
+// dayInMonth возвращает наименьший день месяца, больший after, который является рабочим днем из Days
+func (r BusinessMonthly) dayInMonth(cal Calendar, year int, month time.Month, after int) (int, bool) {
+	var workdays []int
+	for d := 1; d <= daysIn(year, month); d++ {
+		if cal.Workday(time.Date(year, month, d, 0, 0, 0, 0, time.UTC)) {
+			workdays = append(workdays, d)
+		}
+	}
+	best := 0
+	for _, n := range r.Days {
+		i := n - 1
+		if n < 0 {
+			i = len(workdays) + n
+		}
+		if i < 0 || i >= len(workdays) {
+			continue
+		}
+		if d := workdays[i]; d > after && (best == 0 || d < best) {
+			best = d
+		}
+	}
+	return best, best > 0
+}
+
+// after возвращает первую дату после now, рабочие дни месяца не зависят от начальной даты
+func (r BusinessMonthly) after(start, now time.Time) (time.Time, bool) {
+	return r.Next(later(start, now))
+}
@@ -0,0 +1,478 @@
+package repeater
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// языки описания правил
+const (
+	LangRussian = "ru"
+	LangEnglish = "en"
+)
+
+// названия дней недели и месяцев для описаний, индекс соответствует номеру дня или месяца
+var (
+	ruWeekdaysDative = []string{"", "понедельникам", "вторникам", "средам", "четвергам", "пятницам", "субботам", "воскресеньям"}
+	ruWeekdaysAccus  = []string{"", "понедельник", "вторник", "среду", "четверг", "пятницу", "субботу", "воскресенье"}
+	ruMonthsGenitive = []string{"", "января", "февраля", "марта", "апреля", "мая", "июня",
+		"июля", "августа", "сентября", "октября", "ноября", "декабря"}
+	ruMonthsPrepos = []string{"", "январе", "феврале", "марте", "апреле", "мае", "июне",
+		"июле", "августе", "сентябре", "октябре", "ноябре", "декабре"}
+	enWeekdays = []string{"", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+	enMonths   = []string{"", "January", "February", "March", "April", "May", "June",
+		"July", "August", "September", "October", "November", "December"}
+	enOrdinals = []string{"", "first", "second", "third", "fourth", "fifth"}
+)
+
+// ruOrdinals - порядковые числительные в винительном падеже для мужского, женского и среднего рода
+var ruOrdinals = [][3]string{
+	{},
+	{"первый", "первую", "первое"},
+	{"второй", "вторую", "второе"},
+	{"третий", "третью", "третье"},
+	{"четвертый", "четвертую", "четвертое"},
+	{"пятый", "пятую", "пятое"},
+}
+
+// Describe возвращает описание правила на естественном языке: LangRussian или LangEnglish,
+// для остальных языков описание дается на русском
+func Describe(rule Rule, lang string) string {
+	if lang == LangEnglish {
+		return describeEnglish(rule)
+	}
+	return describeRussian(rule)
+}
+
+func describeRussian(rule Rule) string {
+	switch r := rule.(type) {
+	case Daily:
+		if r.Interval == 1 {
+			return "каждый день"
+		}
+		return ruEvery(r.Interval) + " " + strconv.Itoa(r.Interval) + " " + ruPlural(r.Interval, "день", "дня", "дней")
+	case Weekly:
+		days := ruWeekdays(r.Weekdays)
+		if r.weeks() == 1 {
+			return days
+		}
+		return "раз в " + strconv.Itoa(r.weeks()) + " " + ruPlural(r.weeks(), "неделю", "недели", "недель") + " " + days
+	case Monthly:
+		items := make([]string, 0, len(r.Days)+len(r.Weekdays))
+		numbers := true
+		for _, d := range chronological(r.Days) {
+			switch d {
+			case -1:
+				items, numbers = append(items, "в последний день"), false
+			case -2:
+				items, numbers = append(items, "в предпоследний день"), false
+			default:
+				items = append(items, strconv.Itoa(d)+"-го")
+			}
+		}
+		for _, w := range chronologicalWeekdays(r.Weekdays) {
+			items, numbers = append(items, ruNthWeekday(w)), false
+		}
+		text := ruJoin(items)
+		if numbers {
+			text += " числа"
+		}
+		return text + " " + ruMonthsOf(r.Months)
+	case Yearly:
+		return "каждый год"
+	case Business:
+		if r.Interval == 1 {
+			return "каждый рабочий день"
+		}
+		return ruEvery(r.Interval) + " " + strconv.Itoa(r.Interval) + " " +
+			ruPlural(r.Interval, "рабочий день", "рабочих дня", "рабочих дней")
+	case BusinessMonthly:
+		items := make([]string, 0, len(r.Days))
+		for _, d := range chronological(r.Days) {
+			switch {
+			case d == -1:
+				items = append(items, "в последний")
+			case d == -2:
+				items = append(items, "в предпоследний")
+			case d < 0:
+				items = append(items, "в "+strconv.Itoa(-d)+"-й с конца")
+			default:
+				items = append(items, "в "+strconv.Itoa(d)+"-й")
+			}
+		}
+		return ruJoin(items) + " рабочий день " + ruMonthsOf(r.Months)
+	case RRule:
+		return r.describeRussian()
+	}
+	return rule.String()
+}
+
+func describeEnglish(rule Rule) string {
+	switch r := rule.(type) {
+	case Daily:
+		if r.Interval == 1 {
+			return "every day"
+		}
+		return "every " + strconv.Itoa(r.Interval) + " days"
+	case Weekly:
+		days := enWeekdayList(r.Weekdays)
+		if r.weeks() == 1 {
+			return days
+		}
+		return "every " + strconv.Itoa(r.weeks()) + " weeks " + days
+	case Monthly:
+		items := make([]string, 0, len(r.Days)+len(r.Weekdays))
+		for _, d := range chronological(r.Days) {
+			switch d {
+			case -1:
+				items = append(items, "last day")
+			case -2:
+				items = append(items, "second-to-last day")
+			default:
+				items = append(items, enOrdinal(d))
+			}
+		}
+		for _, w := range chronologicalWeekdays(r.Weekdays) {
+			items = append(items, enNthWeekday(w))
+		}
+		return "on the " + enJoin(items) + " of " + enMonthsOf(r.Months)
+	case Yearly:
+		return "every year"
+	case Business:
+		if r.Interval == 1 {
+			return "every working day"
+		}
+		return "every " + strconv.Itoa(r.Interval) + " working days"
+	case BusinessMonthly:
+		items := make([]string, 0, len(r.Days))
+		for _, d := range chronological(r.Days) {
+			switch {
+			case d == -1:
+				items = append(items, "last")
+			case d == -2:
+				items = append(items, "second-to-last")
+			case d < 0:
+				items = append(items, enOrdinal(-d)+"-to-last")
+			default:
+				items = append(items, enOrdinal(d))
+			}
+		}
+		return "on the " + enJoin(items) + " working day of " + enMonthsOf(r.Months)
+	case RRule:
+		return r.describeEnglish()
+	}
+	return rule.String()
+}
+
+// describeRussian описывает правило RRULE: период, дни и месяцы, затем ограничения COUNT и UNTIL
+func (r RRule) describeRussian() string {
+	n := r.interval()
+	var parts []string
+	switch r.Freq {
+	case FreqDaily:
+		parts = append(parts, describeRussian(Daily{Interval: n}))
+	case FreqWeekly:
+		if n == 1 {
+			parts = append(parts, "каждую неделю")
+		} else {
+			parts = append(parts, "раз в "+strconv.Itoa(n)+" "+ruPlural(n, "неделю", "недели", "недель"))
+		}
+	case FreqMonthly:
+		if n == 1 {
+			parts = append(parts, "каждый месяц")
+		} else {
+			parts = append(parts, "раз в "+strconv.Itoa(n)+" "+ruPlural(n, "месяц", "месяца", "месяцев"))
+		}
+	case FreqYearly:
+		if n == 1 {
+			parts = append(parts, "каждый год")
+		} else {
+			parts = append(parts, "раз в "+strconv.Itoa(n)+" "+ruPlural(n, "год", "года", "лет"))
+		}
+	}
+
+	var every []int
+	var days []string
+	for _, w := range chronologicalWeekdays(r.ByDay) {
+		if w.N == 0 {
+			every = append(every, w.Weekday)
+		} else {
+			days = append(days, ruNthWeekday(w))
+		}
+	}
+	if len(every) > 0 {
+		parts = append(parts, ruWeekdays(every))
+	}
+	if len(r.ByMonthDay) > 0 {
+		numbers := make([]string, 0, len(r.ByMonthDay))
+		for _, d := range chronological(r.ByMonthDay) {
+			switch {
+			case d == -1:
+				numbers = append(numbers, "в последний день")
+			case d < 0:
+				numbers = append(numbers, "в "+strconv.Itoa(-d)+"-й с конца день")
+			default:
+				numbers = append(numbers, strconv.Itoa(d)+"-го числа")
+			}
+		}
+		days = append(numbers, days...)
+	}
+	if len(days) > 0 {
+		parts = append(parts, ruJoin(days))
+	}
+	if len(r.ByMonth) > 0 {
+		months := make([]string, 0, len(r.ByMonth))
+		for _, m := range r.ByMonth {
+			months = append(months, ruMonthsPrepos[m])
+		}
+		parts = append(parts, "в "+ruJoin(months))
+	}
+
+	text := strings.Join(parts, " ")
+	if r.Count > 0 {
+		text += ", " + strconv.Itoa(r.Count) + " " + ruPlural(r.Count, "раз", "раза", "раз")
+	}
+	if !r.Until.IsZero() {
+		text += ", до " + r.Until.Format("02.01.2006")
+	}
+	return text
+}
+
+// describeEnglish описывает правило RRULE: период, дни и месяцы, затем ограничения COUNT и UNTIL
+func (r RRule) describeEnglish() string {
+	n := r.interval()
+	unit := map[string]string{FreqDaily: "day", FreqWeekly: "week", FreqMonthly: "month", FreqYearly: "year"}[r.Freq]
+	text := "every " + unit
+	if n > 1 {
+		text = "every " + strconv.Itoa(n) + " " + unit + "s"
+	}
+
+	var every []int
+	var days []string
+	for _, d := range chronological(r.ByMonthDay) {
+		switch {
+		case d == -1:
+			days = append(days, "last day")
+		case d < 0:
+			days = append(days, enOrdinal(-d)+"-to-last day")
+		default:
+			days = append(days, enOrdinal(d))
+		}
+	}
+	for _, w := range chronologicalWeekdays(r.ByDay) {
+		if w.N == 0 {
+			every = append(every, w.Weekday)
+		} else {
+			days = append(days, enNthWeekday(w))
+		}
+	}
+	if len(every) > 0 {
+		text += " " + enWeekdayList(every)
+	}
+	if len(days) > 0 {
+		text += " on the " + enJoin(days)
+	}
+	if len(r.ByMonth) > 0 {
+		months := make([]string, 0, len(r.ByMonth))
+		for _, m := range r.ByMonth {
+			months = append(months, enMonths[m])
+		}
+		text += " in " + enJoin(months)
+	}
+
+	if r.Count == 1 {
+		text += ", once"
+	} else if r.Count > 1 {
+		text += ", " + strconv.Itoa(r.Count) + " times"
+	}
+	if !r.Until.IsZero() {
+		text += ", until " + r.Until.Format("January 2, 2006")
+	}
+	return text
+}
+
+// chronological упорядочивает дни месяца как в календаре: сначала дни с начала месяца, затем дни с конца
+func chronological(days []int) []int {
+	sorted := slices.Clone(days)
+	slices.SortFunc(sorted, func(a, b int) int { return dayOrder(a) - dayOrder(b) })
+	return sorted
+}
+
+// chronologicalWeekdays упорядочивает дни недели месяца так же: второй вторник раньше последней пятницы
+func chronologicalWeekdays(weekdays []NthWeekday) []NthWeekday {
+	sorted := slices.Clone(weekdays)
+	slices.SortFunc(sorted, func(a, b NthWeekday) int {
+		if a.N != b.N {
+			return dayOrder(a.N) - dayOrder(b.N)
+		}
+		return a.Weekday - b.Weekday
+	})
+	return sorted
+}
+
+// dayOrder - ключ сортировки номера дня: отрицательные номера отсчитываются от конца месяца
+func dayOrder(n int) int {
+	if n < 0 {
+		return 100 + n
+	}
+	return n
+}
+
+// ruPlural выбирает форму слова для числа n: 1 день, 2 дня, 5 дней
+func ruPlural(n int, one, few, many string) string {
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return one
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return few
+	}
+	return many
+}
+
+// ruEvery согласует слово "каждый" с числом: каждый 21 день, каждые 3 дня
+func ruEvery(n int) string {
+	if n%10 == 1 && n%100 != 11 {
+		return "каждый"
+	}
+	return "каждые"
+}
+
+// ruJoin объединяет элементы перечисления: "а, б и в"
+func ruJoin(items []string) string {
+	if len(items) <= 1 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " и " + items[len(items)-1]
+}
+
+// ruWeekdays описывает дни недели: "по понедельникам и четвергам", все дни - "каждый день"
+func ruWeekdays(weekdays []int) string {
+	if len(weekdays) == 7 {
+		return "каждый день"
+	}
+	names := make([]string, 0, len(weekdays))
+	for _, w := range weekdays {
+		names = append(names, ruWeekdaysDative[w])
+	}
+	return "по " + ruJoin(names)
+}
+
+// ruNthWeekday описывает N-й день недели месяца: "во второй вторник", "в последнюю пятницу"
+func ruNthWeekday(w NthWeekday) string {
+	gender := 0 // мужской род: понедельник, вторник, четверг
+	switch w.Weekday {
+	case 3, 5, 6:
+		gender = 1
+	case 7:
+		gender = 2
+	}
+	last := [3]string{"последний", "последнюю", "последнее"}
+	prelast := [3]string{"предпоследний", "предпоследнюю", "предпоследнее"}
+
+	var ordinal string
+	switch {
+	case w.N == -1:
+		ordinal = last[gender]
+	case w.N == -2:
+		ordinal = prelast[gender]
+	case w.N < 0:
+		ordinal = ruOrdinal(-w.N, gender) + " с конца"
+	default:
+		ordinal = ruOrdinal(w.N, gender)
+	}
+	preposition := "в "
+	if w.N == 2 {
+		preposition = "во "
+	}
+	return preposition + ordinal + " " + ruWeekdaysAccus[w.Weekday]
+}
+
+// ruOrdinal возвращает порядковое числительное в нужном роде, начиная с шестого - цифрами: "20-й", "20-ю"
+func ruOrdinal(n, gender int) string {
+	if n < len(ruOrdinals) {
+		return ruOrdinals[n][gender]
+	}
+	return strconv.Itoa(n) + [3]string{"-й", "-ю", "-е"}[gender]
+}
+
+// ruMonthsOf описывает месяцы правила: "марта и июня", без месяцев - "каждого месяца"
+func ruMonthsOf(months []int) string {
+	if len(months) == 0 {
+		return "каждого месяца"
+	}
+	names := make([]string, 0, len(months))
+	for _, m := range months {
+		names = append(names, ruMonthsGenitive[m])
+	}
+	return ruJoin(names)
+}
+
+// enJoin объединяет элементы перечисления: "a, b and c"
+func enJoin(items []string) string {
+	if len(items) <= 1 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
+
+// enOrdinal записывает порядковое числительное: 1st, 2nd, 3rd, 11th, 21st
+func enOrdinal(n int) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// enWeekdayList описывает дни недели: "on Monday and Thursday", все дни - "every day"
+func enWeekdayList(weekdays []int) string {
+	if len(weekdays) == 7 {
+		return "every day"
+	}
+	names := make([]string, 0, len(weekdays))
+	for _, w := range weekdays {
+		names = append(names, enWeekdays[w])
+	}
+	return "on " + enJoin(names)
+}
+
+// enNthWeekday описывает N-й день недели месяца: "second Tuesday", "last Friday"
+func enNthWeekday(w NthWeekday) string {
+	switch {
+	case w.N == -1:
+		return "last " + enWeekdays[w.Weekday]
+	case w.N == -2:
+		return "second-to-last " + enWeekdays[w.Weekday]
+	case w.N < 0:
+		return enOrdinalWord(-w.N) + "-to-last " + enWeekdays[w.Weekday]
+	}
+	return enOrdinalWord(w.N) + " " + enWeekdays[w.Weekday]
+}
+
+// enOrdinalWord записывает порядковое числительное словом, начиная с шестого - цифрами: "20th"
+func enOrdinalWord(n int) string {
+	if n < len(enOrdinals) {
+		return enOrdinals[n]
+	}
+	return enOrdinal(n)
+}
+
+// enMonthsOf описывает месяцы правила: "March and June", без месяцев - "every month"
+func enMonthsOf(months []int) string {
+	if len(months) == 0 {
+		return "every month"
+	}
+	names := make([]string, 0, len(months))
+	for _, m := range months {
+		names = append(names, enMonths[m])
+	}
+	return enJoin(names)
+}
@@ -3,8 +3,7 @@ package repeater
 import (
 	"errors"
 	"fmt"
-	"strconv"
-	"strings"
+	"iter"
 	"time"
 
 	"github.com/eOne007/final-project-yapr/pkg/db"
@@ -24,163 +23,128 @@ func AfterNow(date, now time.Time) bool {
 	return d0 > d1
 }
 
+// Today возвращает текущую дату в часовом поясе loc как полночь UTC: правила повторения
+// считают даты целыми днями в UTC, поэтому "сегодня" определяется в часовом поясе задачи один раз
+func Today(now time.Time, loc *time.Location) time.Time {
+	return day(now.In(loc))
+}
+
 // NextDate возвращает следующую дату повторения задачи, с учетом начальной даты и правил повторения
+// Рабочие дни считаются с понедельника по пятницу
 func NextDate(now time.Time, dstart string, repeat string) (string, error) {
+	return NextDateIn(nil, now, dstart, repeat)
+}
+
+// NextDateIn возвращает следующую дату повторения задачи, рабочие дни считаются по календарю cal
+func NextDateIn(cal Calendar, now time.Time, dstart string, repeat string) (string, error) {
 	date, err := time.Parse(db.DateFormat, dstart)
 	if err != nil {
 		return "", fmt.Errorf("incorrect date format: %w", err)
 	}
-	notEmptyRepeat := strings.TrimSpace(repeat)
-	if notEmptyRepeat == "" {
-		return "", errors.New("incorrect repeat rule")
+	rule, err := Parse(repeat)
+	if err != nil {
+		return "", err
 	}
-	partsRepeat := strings.SplitN(notEmptyRepeat, " ", 2)
-
-	switch partsRepeat[0] {
-	case "d":
-		return nextDailyDate(now, date, partsRepeat)
-	case "w":
-		return nextWeeklyDate(now, date, partsRepeat)
-	case "m":
-		return nextMonthlyDate(now, date, partsRepeat)
-	case "y":
-		return nextYearlyDate(now, date)
-	default:
-		return "", fmt.Errorf("incorrect repeat rule: %s", partsRepeat[0])
+	next, ok := After(UseCalendar(rule, cal), date, now)
+	if !ok {
+		return "", errors.New("repeat rule has no dates after now")
 	}
+	return next.Format(db.DateFormat), nil
 }
 
-// nextDailyDate возвращает следующую дату для ежедневного правила с заданным интервалом
-func nextDailyDate(now time.Time, date time.Time, partsRepeat []string) (string, error) {
-	if len(partsRepeat) != 2 {
-		return "", errors.New("incorrect daily repeat rule format")
-	}
-	numberOfDays, err := strconv.Atoi(strings.TrimSpace(partsRepeat[1]))
-	if err != nil {
-		return "", fmt.Errorf("incorrect number of days in repeat rule: %w", err)
-	}
-	if numberOfDays < 1 || numberOfDays > 400 {
-		return "", fmt.Errorf("number of days must be between 1 and 400, got: %d", numberOfDays)
-	}
-	date = date.AddDate(0, 0, numberOfDays)
-	for !AfterNow(date, now) {
-		date = date.AddDate(0, 0, numberOfDays)
-	}
-	return date.Format(db.DateFormat), nil
+// jumper - правило, которое вычисляет первую дату после now сразу, без перебора промежуточных дат
+type jumper interface {
+	after(start, now time.Time) (time.Time, bool)
 }
 
-// nextWeeklyDate возвращает следующую дату для еженедельного правила по дням недели
-func nextWeeklyDate(now time.Time, date time.Time, partsRepeat []string) (string, error) {
-	if len(partsRepeat) != 2 {
-		return "", errors.New("incorrect weekly repeat rule format")
-	}
-	var weekdays[8] bool
-	for _, days := range strings.Split(partsRepeat[1], ",") {
-		day, err := strconv.Atoi(strings.TrimSpace(days))
-			if err != nil || day < 1 || day > 7 {
-				return "", fmt.Errorf("incorrect day of week: %d", day)
-			}
-		weekdays[day] = true
-	}
-	for {
-		dayOfWeek := int(date.Weekday())
-			if dayOfWeek == 0 {
-				dayOfWeek = 7
+// counter - правило с ограниченным числом дат, считая начальную дату
+type counter interface {
+	count() int
+}
+
+// After возвращает первую дату повторения задачи с датой start строго после now
+// Результат совпадает с последовательным вычислением дат от start, как при отметках о выполнении,
+// поэтому он всегда позже start, в том числе для правил w и m с подходящей под правило start в будущем
+func After(rule Rule, start, now time.Time) (time.Time, bool) {
+	if c, ok := rule.(counter); ok && c.count() > 0 {
+		// начальная дата - первая из count дат, остальные перебираются по порядку
+		date, ok := start, true
+		for i := 1; i < c.count(); i++ {
+			if date, ok = rule.Next(date); !ok || AfterNow(date, now) {
+				return date, ok
 			}
-		if weekdays[dayOfWeek] && AfterNow(date, now) {
-			return date.Format(db.DateFormat), nil
 		}
-		date = date.AddDate(0, 0, 1)
+		return time.Time{}, false
 	}
-}
-
-// nextMonthlyDate возвращает следующую дату для ежемесячного правила 
-func nextMonthlyDate(now time.Time, date time.Time, partsRepeat []string) (string, error) {
-	if len(partsRepeat) != 2 {
-		return "", errors.New("incorrect monthly repeat rule format")
+	if j, ok := rule.(jumper); ok {
+		return j.after(start, now)
 	}
-
-	mDetails := strings.SplitN(partsRepeat[1], " ", 2)
-	mDetailsDay := mDetails[0]
-	
-	mDetailsMonth := ""
-	if len(mDetails) == 2{
-		mDetailsMonth = mDetails[1]
+	date, ok := rule.Next(start)
+	for ok && !AfterNow(date, now) {
+		date, ok = rule.Next(date)
 	}
+	return date, ok
+}
 
-	var days [32]bool
-	var lastDay, penultimateDay bool
+// Occurrences возвращает n ближайших дат повторения задачи с начальной датой start строго после after
+func Occurrences(start time.Time, rule string, after time.Time, n int) ([]time.Time, error) {
+	return OccurrencesIn(nil, start, rule, after, n)
+}
 
-	for _, day := range strings.Split(mDetailsDay, ",") {
-		n, err := strconv.Atoi(strings.TrimSpace(day))
-		if err != nil || n < -2 || n == 0 || n > 31 {
-			return "", fmt.Errorf("incorrect day of month: %s", day)
-		}
-		switch n{
-		case -2:
-			penultimateDay = true
-		case -1:
-			lastDay= true
-		default:
-			days[n] = true
-		}
+// OccurrencesIn возвращает n ближайших дат повторения, рабочие дни считаются по календарю cal
+func OccurrencesIn(cal Calendar, start time.Time, rule string, after time.Time, n int) ([]time.Time, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("number of dates must not be negative, got: %d", n)
 	}
-	var months [13]bool
-	if mDetailsMonth == "" {
-		for n := 1; n <= 12; n++ {
-			months[n] = true
-		}
-	} else {
-		for _, month := range strings.Split(mDetailsMonth, ",") {
-			n, err := strconv.Atoi(strings.TrimSpace(month))
-			if err != nil || n < 1 || n > 12 {
-				return "", fmt.Errorf("incorrect month: %s", month)
-			}
-			months[n] = true
-		}
+	dates := make([]time.Time, 0, n)
+	if n == 0 {
+		return dates, nil
 	}
-
-	for {
-		year, month, day := date.Date()
-		numOfMonth := int(month)
-
-		if mDetailsMonth != "" && !months[numOfMonth] {
-			date = time.Date(year, month+1, 1, 0, 0, 0, 0, time.UTC)
+	for date, err := range DatesIn(cal, start, rule, after) {
+		if err != nil {
+			return nil, err
 		}
-
-		lastDayofMonth := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
-
-		if (days[day] ||
-			(penultimateDay && day == lastDayofMonth - 1) ||
-			(lastDay && day == lastDayofMonth)) &&
-			AfterNow(date, now) {
-			return date.Format(db.DateFormat), nil
+		dates = append(dates, date)
+		if len(dates) == n {
+			break
 		}
-		date = date.AddDate(0, 0, 1)
 	}
+	return dates, nil
 }
 
-// nextYearlyDate возвращает следующую дату для ежегодного правила 
-func nextYearlyDate(now time.Time, date time.Time) (string, error) {
-	year := date.Year() + 1
-	month := date.Month()
-	day := date.Day()
-
-	if month == time.February && day == 29 && (year % 4 != 0 || (year % 100 == 0 && year % 400 != 0)) {
-		date = time.Date(year, time.March, 1, 0, 0, 0, 0, time.UTC)
-	} else {
-		date = time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
-	}
-	for !AfterNow(date, now) {
-		year := date.Year() + 1
-		month := date.Month()
-		day := date.Day()
+// Dates перебирает даты повторения задачи с начальной датой start строго после after
+// Каждая следующая дата вычисляется от предыдущей, как при последовательных отметках о выполнении
+// Перебор прекращается вызывающим кодом или когда по правилу больше нет дат,
+// при ошибке в правиле перебор возвращает только ошибку
+func Dates(start time.Time, rule string, after time.Time) iter.Seq2[time.Time, error] {
+	return DatesIn(nil, start, rule, after)
+}
 
-		if month == time.February && day == 29 && (year % 4 != 0 || (year % 100 == 0 && year % 400 != 0)) {
-			date = time.Date(year, time.March, 1, 0, 0, 0, 0, time.UTC)
-		} else {
-			date = time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+// DatesIn перебирает даты повторения задачи, рабочие дни считаются по календарю cal
+func DatesIn(cal Calendar, start time.Time, rule string, after time.Time) iter.Seq2[time.Time, error] {
+	return func(yield func(time.Time, error) bool) {
+		parsed, err := Parse(rule)
+		if err != nil {
+			yield(time.Time{}, err)
+			return
+		}
+		parsed = UseCalendar(parsed, cal)
+		// при ограничении числа дат они перебираются от начальной, которая считается первой из них
+		if c, ok := parsed.(counter); ok && c.count() > 0 {
+			date, ok := start, true
+			for i := 1; i < c.count(); i++ {
+				if date, ok = parsed.Next(date); !ok {
+					return
+				}
+				if AfterNow(date, after) && !yield(date, nil) {
+					return
+				}
+			}
+			return
+		}
+		date, ok := After(parsed, start, after)
+		for ok && yield(date, nil) {
+			date, ok = parsed.Next(date)
 		}
 	}
-	return date.Format(db.DateFormat), nil
 }
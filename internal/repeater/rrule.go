@@ -0,0 +1,526 @@
+package repeater
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// частоты правила RRULE
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+	FreqYearly  = "YEARLY"
+)
+
+// rruleWeekdays - названия дней недели в RRULE, индекс соответствует номеру дня
+var rruleWeekdays = []string{"", "MO", "TU", "WE", "TH", "FR", "SA", "SU"}
+
+// RRule - правило повторения в записи RRULE из RFC 5545
+// Поддерживаются части FREQ, INTERVAL, BYDAY, BYMONTHDAY, BYMONTH, COUNT, UNTIL и WKST.
+// Значения, которые RFC 5545 берет из DTSTART, берутся из начальной даты задачи
+type RRule struct {
+	Freq       string       // DAILY, WEEKLY, MONTHLY или YEARLY
+	Interval   int          // число периодов между повторениями, от 1
+	ByDay      []NthWeekday // N = 0 - каждый такой день недели периода
+	ByMonthDay []int        // от 1 до 31 и от -1 до -31 - с конца месяца
+	ByMonth    []int        // от 1 до 12
+	Wkst       int          // первый день недели, от 1 (понедельник) до 7
+	Count      int          // число дат, считая начальную, 0 - без ограничения
+	Until      time.Time    // последняя дата включительно, нулевое значение - без ограничения
+}
+
+// isRRule проверяет, что запись правила сделана в формате RRULE
+func isRRule(repeat string) bool {
+	upper := strings.ToUpper(strings.TrimSpace(repeat))
+	return strings.HasPrefix(upper, "RRULE:") || strings.Contains(upper, "FREQ=")
+}
+
+// parseRRule разбирает запись RRULE вида [RRULE:]FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH
+func parseRRule(repeat string) (Rule, error) {
+	body := strings.TrimLeft(repeat, " \t")
+	offset := len(repeat) - len(body)
+	if strings.HasPrefix(strings.ToUpper(body), "RRULE:") {
+		offset += len("RRULE:")
+	}
+	// позиция считается в символах с единицы, как и в остальных правилах
+	posAt := func(i int) int { return utf8.RuneCountInString(repeat[:i]) + 1 }
+
+	r := RRule{Interval: 1, Wkst: 1}
+	seen := make(map[string]bool)
+	// номера дней недели BYDAY проверяются после разбора, когда известны FREQ и BYMONTH
+	var byDay ruleField
+	for _, part := range strings.Split(repeat[offset:], ";") {
+		start := offset
+		offset += len(part) + 1
+		trimmed := strings.TrimSpace(part)
+		if trimmed == "" {
+			continue
+		}
+		start += strings.Index(part, trimmed)
+
+		key, value, ok := strings.Cut(trimmed, "=")
+		key = strings.ToUpper(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if !ok || value == "" {
+			return nil, &ParseError{Pos: posAt(start), Token: trimmed, Msg: "expected NAME=VALUE"}
+		}
+		if seen[key] {
+			return nil, &ParseError{Pos: posAt(start), Token: trimmed, Msg: "duplicate " + key}
+		}
+		seen[key] = true
+
+		raw := trimmed[strings.Index(trimmed, "=")+1:]
+		valueStart := start + len(trimmed) - len(strings.TrimLeft(raw, " \t"))
+		field := ruleField{text: strings.ToUpper(value), pos: posAt(valueStart)}
+		fail := func(msg string) error {
+			return &ParseError{Pos: field.pos, Token: value, Msg: msg}
+		}
+
+		var err error
+		switch key {
+		case "FREQ":
+			switch field.text {
+			case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+				r.Freq = field.text
+			default:
+				return nil, fail("FREQ must be DAILY, WEEKLY, MONTHLY or YEARLY")
+			}
+		case "INTERVAL":
+			if r.Interval, err = strconv.Atoi(field.text); err != nil || r.Interval < 1 || r.Interval > 1000 {
+				return nil, fail("INTERVAL must be between 1 and 1000")
+			}
+		case "COUNT":
+			if r.Count, err = strconv.Atoi(field.text); err != nil || r.Count < 1 || r.Count > 100000 {
+				return nil, fail("COUNT must be between 1 and 100000")
+			}
+		case "UNTIL":
+			date, _, _ := strings.Cut(field.text, "T")
+			if r.Until, err = time.Parse("20060102", date); err != nil {
+				return nil, fail("UNTIL must be a date like 20060102 or 20060102T150405Z")
+			}
+		case "WKST":
+			if r.Wkst = slices.Index(rruleWeekdays[1:], field.text) + 1; r.Wkst == 0 {
+				return nil, fail("WKST must be one of MO, TU, WE, TH, FR, SA, SU")
+			}
+		case "BYMONTH":
+			if r.ByMonth, err = parseList(field, 1, 12, nil, "month must be between 1 and 12"); err != nil {
+				return nil, err
+			}
+		case "BYMONTHDAY":
+			negative := make([]int, 0, 31)
+			for d := -31; d <= -1; d++ {
+				negative = append(negative, d)
+			}
+			r.ByMonthDay, err = parseList(field, 1, 31, negative, "day of month must be between 1 and 31 or -31 and -1")
+			if err != nil {
+				return nil, err
+			}
+		case "BYDAY":
+			byDay = field
+		default:
+			return nil, &ParseError{Pos: posAt(start), Token: key, Msg: "unsupported RRULE part"}
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, &ParseError{Pos: 1, Token: repeat, Msg: "missing FREQ"}
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return nil, &ParseError{Pos: 1, Token: repeat, Msg: "COUNT and UNTIL must not be used together"}
+	}
+	if r.Freq == FreqWeekly && len(r.ByMonthDay) > 0 {
+		return nil, &ParseError{Pos: 1, Token: repeat, Msg: "BYMONTHDAY must not be used with FREQ=WEEKLY"}
+	}
+	if byDay.text != "" {
+		maxN := 0
+		switch {
+		case r.Freq == FreqMonthly || r.Freq == FreqYearly && len(r.ByMonth) > 0:
+			maxN = 5
+		case r.Freq == FreqYearly:
+			maxN = 53
+		}
+		var err error
+		if r.ByDay, err = parseByDay(byDay, maxN); err != nil {
+			return nil, err
+		}
+	}
+
+	// правило, которое не срабатывает ни разу за 400 лет даже с единичным интервалом, не сработает никогда
+	probe := r
+	probe.Interval, probe.Count, probe.Until = 1, 0, time.Time{}
+	seed := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if _, ok := probe.next(seed.AddDate(0, 0, -1), seed); !ok {
+		return nil, &ParseError{Pos: 1, Token: repeat, Msg: "rule never occurs"}
+	}
+	return r, nil
+}
+
+// parseByDay разбирает список дней недели BYDAY вида MO,2TU,-1FR, номер дня недели - от -maxN до maxN
+func parseByDay(field ruleField, maxN int) ([]NthWeekday, error) {
+	var days []NthWeekday
+	pos := field.pos
+	for _, item := range strings.Split(field.text, ",") {
+		value := strings.TrimSpace(item)
+		itemPos := pos + len([]rune(item)) - len([]rune(strings.TrimLeft(item, " \t")))
+		pos += len([]rune(item)) + 1
+
+		fail := &ParseError{Pos: itemPos, Token: value, Msg: "BYDAY must be a weekday like MO, 2TU or -1FR"}
+		if len(value) < 2 {
+			return nil, fail
+		}
+		weekday := slices.Index(rruleWeekdays[1:], value[len(value)-2:]) + 1
+		if weekday == 0 {
+			return nil, fail
+		}
+		n := 0
+		if number := value[:len(value)-2]; number != "" {
+			var err error
+			if n, err = strconv.Atoi(number); err != nil || n == 0 {
+				return nil, fail
+			}
+			if maxN == 0 {
+				return nil, &ParseError{Pos: itemPos, Token: value, Msg: "numbered BYDAY is only allowed with FREQ=MONTHLY or FREQ=YEARLY"}
+			}
+			if n > maxN || n < -maxN {
+				return nil, &ParseError{Pos: itemPos, Token: value, Msg: "BYDAY number must be between 1 and " + strconv.Itoa(maxN) + " or -" + strconv.Itoa(maxN) + " and -1"}
+			}
+		}
+		day := NthWeekday{N: n, Weekday: weekday}
+		if !slices.Contains(days, day) {
+			days = append(days, day)
+		}
+	}
+	slices.SortFunc(days, compareNthWeekday)
+	return days, nil
+}
+
+// compareNthWeekday упорядочивает дни недели по номеру, затем по дню недели
+func compareNthWeekday(a, b NthWeekday) int {
+	if a.N != b.N {
+		return a.N - b.N
+	}
+	return a.Weekday - b.Weekday
+}
+
+// rruleString записывает день недели в формате BYDAY
+func (w NthWeekday) rruleString() string {
+	if w.N == 0 {
+		return rruleWeekdays[w.Weekday]
+	}
+	return strconv.Itoa(w.N) + rruleWeekdays[w.Weekday]
+}
+
+func (r RRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, w := range r.ByDay {
+			days = append(days, w.rruleString())
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinList(r.ByMonthDay))
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinList(r.ByMonth))
+	}
+	if r.Wkst > 1 {
+		parts = append(parts, "WKST="+rruleWeekdays[r.Wkst])
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next возвращает ближайшую после after дату правила, значения по умолчанию берутся из after
+// Ограничение COUNT здесь не учитывается: для него нужна начальная дата, его учитывают After и Dates
+func (r RRule) Next(after time.Time) (time.Time, bool) {
+	return r.next(day(after), day(after))
+}
+
+// after возвращает первую дату после now без перебора периодов до now
+// Подходят только периоды, отстоящие от периода start на кратное Interval число периодов
+func (r RRule) after(start, now time.Time) (time.Time, bool) {
+	start, now = day(start), day(now)
+	if !now.After(start) {
+		return r.next(start, start)
+	}
+	first := r.periodStart(start)
+	k := r.periodsBetween(first, r.periodStart(now))
+	n := r.interval()
+	if k%n == 0 {
+		return r.next(now, start)
+	}
+	// последний день последнего подходящего периода до now: дальше поиск идет с следующего подходящего
+	last := r.addPeriods(r.addPeriods(first, k-k%n), 1).AddDate(0, 0, -1)
+	return r.next(last, start)
+}
+
+// count возвращает ограничение COUNT
+func (r RRule) count() int {
+	return r.Count
+}
+
+// next возвращает ближайшую после after дату, значения по умолчанию берутся из seed
+// Поиск начинается с периода, содержащего after, и идет через Interval периодов
+func (r RRule) next(after, seed time.Time) (time.Time, bool) {
+	rule := r.withDefaults(seed)
+	period := r.periodStart(after)
+	for i := 0; i < r.maxPeriods(); i++ {
+		for _, d := range rule.candidates(period) {
+			if !d.After(after) {
+				continue
+			}
+			if !r.Until.IsZero() && d.After(r.Until) {
+				return time.Time{}, false
+			}
+			return d, true
+		}
+		period = r.addPeriods(period, r.interval())
+		if !r.Until.IsZero() && period.After(r.Until) {
+			return time.Time{}, false
+		}
+	}
+	return time.Time{}, false
+}
+
+// withDefaults дополняет правило значениями из начальной даты, как RFC 5545 дополняет их из DTSTART
+func (r RRule) withDefaults(seed time.Time) RRule {
+	switch r.Freq {
+	case FreqWeekly:
+		if len(r.ByDay) == 0 {
+			r.ByDay = []NthWeekday{{Weekday: isoWeekday(seed)}}
+		}
+	case FreqMonthly:
+		if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 {
+			r.ByMonthDay = []int{seed.Day()}
+		}
+	case FreqYearly:
+		if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 {
+			r.ByMonthDay = []int{seed.Day()}
+			if len(r.ByMonth) == 0 {
+				r.ByMonth = []int{int(seed.Month())}
+			}
+		}
+	}
+	return r
+}
+
+// candidates возвращает по возрастанию все даты правила в периоде, начинающемся с p
+func (r RRule) candidates(p time.Time) []time.Time {
+	var dates []time.Time
+	switch r.Freq {
+	case FreqDaily:
+		if r.matches(p) {
+			dates = append(dates, p)
+		}
+	case FreqWeekly:
+		for i := 0; i < 7; i++ {
+			if d := p.AddDate(0, 0, i); r.matches(d) {
+				dates = append(dates, d)
+			}
+		}
+	case FreqMonthly:
+		dates = r.monthDays(p.Year(), p.Month())
+	case FreqYearly:
+		// номера дней недели без BYMONTH и BYMONTHDAY считаются от начала или конца года
+		if len(r.ByMonth) == 0 && len(r.ByMonthDay) == 0 {
+			return r.yearWeekdays(p.Year())
+		}
+		for m := time.January; m <= time.December; m++ {
+			dates = append(dates, r.monthDays(p.Year(), m)...)
+		}
+	}
+	return dates
+}
+
+// matches проверяет дату по ограничениям BYMONTH, BYMONTHDAY и BYDAY для частот DAILY и WEEKLY
+func (r RRule) matches(d time.Time) bool {
+	if len(r.ByMonth) > 0 && !slices.Contains(r.ByMonth, int(d.Month())) {
+		return false
+	}
+	if len(r.ByMonthDay) > 0 {
+		fromEnd := d.Day() - daysIn(d.Year(), d.Month()) - 1
+		if !slices.Contains(r.ByMonthDay, d.Day()) && !slices.Contains(r.ByMonthDay, fromEnd) {
+			return false
+		}
+	}
+	if len(r.ByDay) > 0 && !slices.ContainsFunc(r.ByDay, func(w NthWeekday) bool { return w.Weekday == isoWeekday(d) }) {
+		return false
+	}
+	return true
+}
+
+// monthDays возвращает по возрастанию даты правила в месяце
+// BYMONTHDAY и BYDAY вместе дают дни, подходящие под оба условия
+func (r RRule) monthDays(year int, month time.Month) []time.Time {
+	if len(r.ByMonth) > 0 && !slices.Contains(r.ByMonth, int(month)) {
+		return nil
+	}
+	last := daysIn(year, month)
+	var weekdays []int
+	for _, w := range r.ByDay {
+		if w.N != 0 {
+			if d, ok := w.day(year, month); ok {
+				weekdays = append(weekdays, d)
+			}
+			continue
+		}
+		first := isoWeekday(time.Date(year, month, 1, 0, 0, 0, 0, time.UTC))
+		for d := 1 + (w.Weekday-first+7)%7; d <= last; d += 7 {
+			weekdays = append(weekdays, d)
+		}
+	}
+
+	var days []int
+	if len(r.ByMonthDay) > 0 {
+		for _, d := range r.ByMonthDay {
+			if d < 0 {
+				d = last + 1 + d
+			}
+			if d >= 1 && d <= last && (len(r.ByDay) == 0 || slices.Contains(weekdays, d)) {
+				days = append(days, d)
+			}
+		}
+	} else {
+		days = weekdays
+	}
+	slices.Sort(days)
+	days = slices.Compact(days)
+
+	dates := make([]time.Time, 0, len(days))
+	for _, d := range days {
+		dates = append(dates, time.Date(year, month, d, 0, 0, 0, 0, time.UTC))
+	}
+	return dates
+}
+
+// yearWeekdays возвращает по возрастанию даты дней недели BYDAY в году,
+// номер дня недели считается от начала или конца года
+func (r RRule) yearWeekdays(year int) []time.Time {
+	jan1 := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	dec31 := time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC)
+	var dates []time.Time
+	for _, w := range r.ByDay {
+		first := jan1.AddDate(0, 0, (w.Weekday-isoWeekday(jan1)+7)%7)
+		lastDay := dec31.AddDate(0, 0, -(isoWeekday(dec31)-w.Weekday+7)%7)
+		switch {
+		case w.N == 0:
+			for d := first; d.Year() == year; d = d.AddDate(0, 0, 7) {
+				dates = append(dates, d)
+			}
+		case w.N > 0:
+			if d := first.AddDate(0, 0, 7*(w.N-1)); d.Year() == year {
+				dates = append(dates, d)
+			}
+		default:
+			if d := lastDay.AddDate(0, 0, 7*(w.N+1)); d.Year() == year {
+				dates = append(dates, d)
+			}
+		}
+	}
+	slices.SortFunc(dates, func(a, b time.Time) int { return a.Compare(b) })
+	return slices.CompactFunc(dates, time.Time.Equal)
+}
+
+// interval возвращает интервал, нулевое значение считается единичным
+func (r RRule) interval() int {
+	return max(r.Interval, 1)
+}
+
+// periodStart возвращает первый день периода правила, содержащего дату t
+func (r RRule) periodStart(t time.Time) time.Time {
+	switch r.Freq {
+	case FreqWeekly:
+		wkst := max(r.Wkst, 1)
+		return t.AddDate(0, 0, -((isoWeekday(t) - wkst + 7) % 7))
+	case FreqMonthly:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case FreqYearly:
+		return time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return t
+}
+
+// addPeriods сдвигает первый день периода на n периодов
+func (r RRule) addPeriods(p time.Time, n int) time.Time {
+	switch r.Freq {
+	case FreqWeekly:
+		return p.AddDate(0, 0, 7*n)
+	case FreqMonthly:
+		return p.AddDate(0, n, 0)
+	case FreqYearly:
+		return p.AddDate(n, 0, 0)
+	}
+	return p.AddDate(0, 0, n)
+}
+
+// periodsBetween возвращает число периодов между первыми днями двух периодов
+func (r RRule) periodsBetween(from, to time.Time) int {
+	switch r.Freq {
+	case FreqWeekly:
+		return daysBetween(from, to) / 7
+	case FreqMonthly:
+		return (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+	case FreqYearly:
+		return to.Year() - from.Year()
+	}
+	return daysBetween(from, to)
+}
+
+// maxPeriods возвращает число периодов в 400-летнем цикле григорианского календаря:
+// если правило не сработало за это время, оно не сработает никогда
+func (r RRule) maxPeriods() int {
+	switch r.Freq {
+	case FreqWeekly:
+		return 20871 + 1
+	case FreqMonthly:
+		return monthsInGregorianCycle
+	case FreqYearly:
+		return 400
+	}
+	return 146097
+}
+
+// ToRRule переводит правило в запись RRULE с теми же датами
+// Правило m, в котором дни месяца смешаны с днями недели, нельзя записать одним RRULE:
+// в RRULE BYMONTHDAY и BYDAY вместе означают пересечение, а не объединение.
+// Для правила y дата 29 февраля в RRULE повторяется только в високосные годы,
+// а не переносится на 1 марта
+func ToRRule(rule Rule) (string, error) {
+	switch r := rule.(type) {
+	case Daily:
+		return RRule{Freq: FreqDaily, Interval: r.Interval}.String(), nil
+	case Weekly:
+		days := make([]NthWeekday, 0, len(r.Weekdays))
+		for _, w := range r.Weekdays {
+			days = append(days, NthWeekday{Weekday: w})
+		}
+		return RRule{Freq: FreqWeekly, Interval: r.weeks(), ByDay: days}.String(), nil
+	case Monthly:
+		if len(r.Days) > 0 && len(r.Weekdays) > 0 {
+			return "", errors.New("rule mixing days of month and weekdays has no single RRULE equivalent")
+		}
+		return RRule{Freq: FreqMonthly, ByMonthDay: r.Days, ByDay: r.Weekdays, ByMonth: r.Months}.String(), nil
+	case Yearly:
+		return RRule{Freq: FreqYearly}.String(), nil
+	case RRule:
+		return r.String(), nil
+	case Business, BusinessMonthly:
+		return "", errors.New("working day rules have no RRULE equivalent")
+	}
+	return "", errors.New("unknown rule type")
+}
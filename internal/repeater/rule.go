@@ -0,0 +1,547 @@
+package repeater
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Rule - разобранное правило повторения задачи
+type Rule interface {
+	// Next возвращает дату повторения, следующую строго после даты after,
+	// ok равно false, если по правилу больше нет дат
+	Next(after time.Time) (next time.Time, ok bool)
+	// String возвращает каноническую текстовую запись правила, которую снова можно разобрать Parse
+	String() string
+}
+
+// Daily - правило d <дни>: повторение через заданное число дней
+type Daily struct {
+	Interval int // от 1 до 400
+}
+
+// Weekly - правило w <дни недели> [/недели]: повторение в указанные дни недели каждые Interval недель
+// Отсчет недель ведется от недели начальной даты задачи
+type Weekly struct {
+	Weekdays []int // от 1 (понедельник) до 7 (воскресенье) по возрастанию
+	Interval int   // от 1 до 52, 1 - каждую неделю
+}
+
+// Monthly - правило m <дни месяца> [месяцы]: повторение в указанные дни указанных месяцев
+type Monthly struct {
+	Days     []int        // от 1 до 31, -1 - последний и -2 - предпоследний день месяца, по возрастанию
+	Weekdays []NthWeekday // дни недели с порядковым номером в месяце, например 2tue или -1fri
+	Months   []int        // от 1 до 12 по возрастанию, пустой список - каждый месяц
+}
+
+// NthWeekday - N-й день недели в месяце: N от 1 до 5 считается с начала месяца, от -1 до -5 - с конца
+type NthWeekday struct {
+	N       int
+	Weekday int // от 1 (понедельник) до 7 (воскресенье)
+}
+
+// weekdayNames - сокращенные названия дней недели в записи правила, индекс соответствует номеру дня
+var weekdayNames = []string{"", "mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+
+// Yearly - правило y: повторение раз в год, 29 февраля в невисокосный год переносится на 1 марта
+type Yearly struct{}
+
+// ParseError - ошибка разбора правила повторения с позицией ошибочного элемента
+// Позиция считается в символах с единицы
+type ParseError struct {
+	Pos   int
+	Token string
+	Msg   string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("incorrect repeat rule at position %d (%q): %s", e.Pos, e.Token, e.Msg)
+}
+
+// ruleField - элемент записи правила вместе с его позицией
+type ruleField struct {
+	text string
+	pos  int
+}
+
+// Parse разбирает текстовую запись правила повторения: d <дни>, w <дни недели> [/недели],
+// m <дни месяца> [месяцы], y, b <рабочие дни>, bm <рабочие дни месяца> [месяцы] или RRULE из RFC 5545
+// Правила b и bm считают рабочие дни с понедельника по пятницу, календарь праздников задает UseCalendar
+func Parse(repeat string) (Rule, error) {
+	if isRRule(repeat) {
+		return parseRRule(repeat)
+	}
+	fields := splitRule(repeat)
+	if len(fields) == 0 {
+		return nil, &ParseError{Pos: 1, Token: repeat, Msg: "empty rule"}
+	}
+	kind, args := fields[0], fields[1:]
+	if kind.text == "w" && len(args) > 0 {
+		args = splitInterval(args)
+	}
+
+	maxArgs := map[string]int{"d": 1, "w": 2, "m": 2, "y": 0, "b": 1, "bm": 2}
+	n, ok := maxArgs[kind.text]
+	if !ok {
+		return nil, &ParseError{Pos: kind.pos, Token: kind.text, Msg: "unknown rule type, expected d, w, m, y, b or bm"}
+	}
+	if len(args) > n {
+		return nil, &ParseError{Pos: args[n].pos, Token: args[n].text, Msg: "unexpected value"}
+	}
+	if kind.text != "y" && len(args) == 0 {
+		return nil, &ParseError{Pos: len([]rune(repeat)) + 1, Token: kind.text, Msg: "missing value"}
+	}
+
+	switch kind.text {
+	case "d":
+		days, err := parseList(args[0], 1, 400, nil, "number of days must be between 1 and 400")
+		if err != nil {
+			return nil, err
+		}
+		if len(days) != 1 {
+			return nil, &ParseError{Pos: args[0].pos, Token: args[0].text, Msg: "expected a single number of days"}
+		}
+		return Daily{Interval: days[0]}, nil
+	case "w":
+		if strings.HasPrefix(args[0].text, "/") {
+			return nil, &ParseError{Pos: args[0].pos, Token: args[0].text, Msg: "missing days of week before interval"}
+		}
+		weekdays, err := parseList(args[0], 1, 7, nil, "day of week must be between 1 and 7")
+		if err != nil {
+			return nil, err
+		}
+		rule := Weekly{Weekdays: weekdays, Interval: 1}
+		if len(args) == 2 {
+			if !strings.HasPrefix(args[1].text, "/") {
+				return nil, &ParseError{Pos: args[1].pos, Token: args[1].text, Msg: "unexpected value"}
+			}
+			field := ruleField{text: args[1].text[1:], pos: args[1].pos + 1}
+			weeks, err := parseList(field, 1, 52, nil, "number of weeks must be between 1 and 52")
+			if err != nil {
+				return nil, err
+			}
+			if len(weeks) != 1 {
+				return nil, &ParseError{Pos: field.pos, Token: field.text, Msg: "expected a single number of weeks"}
+			}
+			rule.Interval = weeks[0]
+		}
+		return rule, nil
+	case "m":
+		days, weekdays, err := parseMonthDays(args[0])
+		if err != nil {
+			return nil, err
+		}
+		rule := Monthly{Days: days, Weekdays: weekdays}
+		if len(args) == 2 {
+			rule.Months, err = parseList(args[1], 1, 12, nil, "month must be between 1 and 12")
+			if err != nil {
+				return nil, err
+			}
+			if !rule.possible() {
+				return nil, &ParseError{Pos: args[1].pos, Token: args[1].text, Msg: "none of the days occurs in these months"}
+			}
+		}
+		return rule, nil
+	case "b":
+		days, err := parseList(args[0], 1, 400, nil, "number of working days must be between 1 and 400")
+		if err != nil {
+			return nil, err
+		}
+		if len(days) != 1 {
+			return nil, &ParseError{Pos: args[0].pos, Token: args[0].text, Msg: "expected a single number of working days"}
+		}
+		return Business{Interval: days[0]}, nil
+	case "bm":
+		negative := make([]int, 0, maxBusinessDays)
+		for d := -maxBusinessDays; d <= -1; d++ {
+			negative = append(negative, d)
+		}
+		days, err := parseList(args[0], 1, maxBusinessDays, negative, "working day of month must be between 1 and 23 or -23 and -1")
+		if err != nil {
+			return nil, err
+		}
+		rule := BusinessMonthly{Days: days}
+		if len(args) == 2 {
+			if rule.Months, err = parseList(args[1], 1, 12, nil, "month must be between 1 and 12"); err != nil {
+				return nil, err
+			}
+		}
+		return rule, nil
+	}
+	return Yearly{}, nil
+}
+
+// splitRule разбивает запись правила на элементы, разделенные пробелами
+// Пробелы рядом с запятой не разделяют элементы, поэтому запись "w 1, 3" остается допустимой
+func splitRule(repeat string) []ruleField {
+	runes := []rune(repeat)
+	var fields []ruleField
+	var current []rune
+	start := 0
+	for i := 0; i <= len(runes); i++ {
+		if i < len(runes) && !unicode.IsSpace(runes[i]) {
+			if len(current) == 0 {
+				start = i
+			}
+			current = append(current, runes[i])
+			continue
+		}
+		next := i
+		for next < len(runes) && unicode.IsSpace(runes[next]) {
+			next++
+		}
+		joined := len(current) > 0 && next < len(runes) &&
+			(current[len(current)-1] == ',' || runes[next] == ',')
+		if joined {
+			current = append(current, runes[i:next]...)
+		} else if len(current) > 0 {
+			fields = append(fields, ruleField{text: string(current), pos: start + 1})
+			current = nil
+		}
+		if next > i {
+			i = next - 1
+		}
+	}
+	return fields
+}
+
+// splitInterval отделяет интервал недель, записанный слитно с днями недели: "1,4/2" или "1,4 /2"
+func splitInterval(args []ruleField) []ruleField {
+	i := strings.Index(args[0].text, "/")
+	if i <= 0 {
+		return args
+	}
+	days := ruleField{text: strings.TrimSpace(args[0].text[:i]), pos: args[0].pos}
+	interval := ruleField{text: args[0].text[i:], pos: args[0].pos + len([]rune(args[0].text[:i]))}
+	return append([]ruleField{days, interval}, args[1:]...)
+}
+
+// parseList разбирает список чисел через запятую от min до max или из списка extra,
+// возвращает уникальные значения по возрастанию
+func parseList(field ruleField, min, max int, extra []int, msg string) ([]int, error) {
+	var values []int
+	pos := field.pos
+	for _, item := range strings.Split(field.text, ",") {
+		value := strings.TrimSpace(item)
+		itemPos := pos + len([]rune(item)) - len([]rune(strings.TrimLeft(item, " \t")))
+		pos += len([]rune(item)) + 1
+
+		n, err := strconv.Atoi(value)
+		if err != nil || (n < min || n > max) && !slices.Contains(extra, n) {
+			return nil, &ParseError{Pos: itemPos, Token: value, Msg: msg}
+		}
+		if !slices.Contains(values, n) {
+			values = append(values, n)
+		}
+	}
+	slices.Sort(values)
+	return values, nil
+}
+
+// parseMonthDays разбирает список дней месяца: числа от 1 до 31, -1, -2
+// и дни недели с порядковым номером вида 2tue или -1fri
+// Возвращает уникальные значения: числа по возрастанию, дни недели по номеру и дню недели
+func parseMonthDays(field ruleField) ([]int, []NthWeekday, error) {
+	var days []int
+	var weekdays []NthWeekday
+	pos := field.pos
+	for _, item := range strings.Split(field.text, ",") {
+		value := strings.TrimSpace(item)
+		itemPos := pos + len([]rune(item)) - len([]rune(strings.TrimLeft(item, " \t")))
+		pos += len([]rune(item)) + 1
+
+		if nth, ok, err := parseNthWeekday(value); ok {
+			if err != nil {
+				return nil, nil, &ParseError{Pos: itemPos, Token: value, Msg: err.Error()}
+			}
+			if !slices.Contains(weekdays, nth) {
+				weekdays = append(weekdays, nth)
+			}
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || (n < 1 || n > 31) && n != -1 && n != -2 {
+			return nil, nil, &ParseError{Pos: itemPos, Token: value,
+				Msg: "day of month must be between 1 and 31, -1, -2 or a weekday like 2tue"}
+		}
+		if !slices.Contains(days, n) {
+			days = append(days, n)
+		}
+	}
+	slices.Sort(days)
+	slices.SortFunc(weekdays, compareNthWeekday)
+	return days, weekdays, nil
+}
+
+// parseNthWeekday разбирает день недели с порядковым номером, например 2tue
+// ok равно false, если значение не оканчивается названием дня недели
+func parseNthWeekday(value string) (NthWeekday, bool, error) {
+	lower := strings.ToLower(value)
+	if len(lower) < 3 {
+		return NthWeekday{}, false, nil
+	}
+	weekday := slices.Index(weekdayNames[1:], lower[len(lower)-3:]) + 1
+	if weekday == 0 {
+		return NthWeekday{}, false, nil
+	}
+	n, err := strconv.Atoi(lower[:len(lower)-3])
+	if err != nil || n == 0 || n < -5 || n > 5 {
+		return NthWeekday{}, true, fmt.Errorf("weekday number must be between 1 and 5 or -1 and -5")
+	}
+	return NthWeekday{N: n, Weekday: weekday}, true, nil
+}
+
+func (w NthWeekday) String() string {
+	return strconv.Itoa(w.N) + weekdayNames[w.Weekday]
+}
+
+// day возвращает день месяца для N-го дня недели или false, если в месяце нет такого дня
+func (w NthWeekday) day(year int, month time.Month) (int, bool) {
+	last := daysIn(year, month)
+	if w.N > 0 {
+		first := isoWeekday(time.Date(year, month, 1, 0, 0, 0, 0, time.UTC))
+		d := 1 + (w.Weekday-first+7)%7 + 7*(w.N-1)
+		return d, d <= last
+	}
+	lastWeekday := isoWeekday(time.Date(year, month, last, 0, 0, 0, 0, time.UTC))
+	d := last - (lastWeekday-w.Weekday+7)%7 + 7*(w.N+1)
+	return d, d >= 1
+}
+
+// joinList записывает список чисел через запятую
+func joinList(values []int) string {
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		parts = append(parts, strconv.Itoa(v))
+	}
+	return strings.Join(parts, ",")
+}
+
+// day возвращает полночь UTC даты t
+func day(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func (r Daily) String() string {
+	return "d " + strconv.Itoa(r.Interval)
+}
+
+// Next возвращает дату через Interval дней после after
+func (r Daily) Next(after time.Time) (time.Time, bool) {
+	return day(after).AddDate(0, 0, r.Interval), true
+}
+
+// after возвращает первую дату start + k*Interval, k >= 1, позже now без перебора промежуточных дат
+func (r Daily) after(start, now time.Time) (time.Time, bool) {
+	start = day(start)
+	k := 1
+	if elapsed := daysBetween(start, day(now)); elapsed >= 0 {
+		k = elapsed/r.Interval + 1
+	}
+	return start.AddDate(0, 0, k*r.Interval), true
+}
+
+func (r Weekly) String() string {
+	if r.weeks() == 1 {
+		return "w " + joinList(r.Weekdays)
+	}
+	return "w " + joinList(r.Weekdays) + " /" + strconv.Itoa(r.Interval)
+}
+
+// weeks возвращает интервал в неделях, нулевое значение считается каждой неделей
+func (r Weekly) weeks() int {
+	return max(r.Interval, 1)
+}
+
+// Next возвращает ближайший после after день из Weekdays в той же неделе,
+// а если его нет - первый из Weekdays через Interval недель после недели after
+func (r Weekly) Next(after time.Time) (time.Time, bool) {
+	if len(r.Weekdays) == 0 {
+		return time.Time{}, false
+	}
+	current := isoWeekday(after)
+	for _, weekday := range r.Weekdays {
+		if weekday > current {
+			return day(after).AddDate(0, 0, weekday-current), true
+		}
+	}
+	return day(after).AddDate(0, 0, 7*r.weeks()+r.Weekdays[0]-current), true
+}
+
+// after возвращает первую дату после now без перебора недель
+// Подходят только недели, отстоящие от недели start на кратное Interval число недель
+func (r Weekly) after(start, now time.Time) (time.Time, bool) {
+	if !AfterNow(now, start) {
+		return r.Next(start)
+	}
+	n := r.weeks()
+	monday := day(start).AddDate(0, 0, 1-isoWeekday(start))
+	k := daysBetween(monday, day(now)) / 7
+	if k%n == 0 {
+		return r.Next(now)
+	}
+	// воскресенье последней подходящей недели до now: следующая дата - в первой подходящей неделе после now
+	return r.Next(monday.AddDate(0, 0, 7*(k-k%n)+6))
+}
+
+func (r Monthly) String() string {
+	days := joinList(r.Days)
+	for _, w := range r.Weekdays {
+		if days != "" {
+			days += ","
+		}
+		days += w.String()
+	}
+	if len(r.Months) == 0 {
+		return "m " + days
+	}
+	return "m " + days + " " + joinList(r.Months)
+}
+
+// Next возвращает ближайший после after день из Days или Weekdays в одном из месяцев Months
+// Месяцы перебираются целиком, день внутри месяца вычисляется сразу
+func (r Monthly) Next(after time.Time) (time.Time, bool) {
+	year, month, d := after.Date()
+	for i := 0; i < r.searchMonths(); i++ {
+		if len(r.Months) == 0 || slices.Contains(r.Months, int(month)) {
+			if next, ok := r.dayInMonth(year, month, d); ok {
+				return time.Date(year, month, next, 0, 0, 0, 0, time.UTC), true
+			}
+		}
+		month++
+		if month > time.December {
+			year, month = year+1, time.January
+		}
+		d = 0
+	}
+	return time.Time{}, false
+}
+
+// searchMonths возвращает, сколько месяцев нужно перебрать, чтобы найти дату по возможному правилу
+// Числа месяца повторяются хотя бы раз за 8 лет: 29 февраля в худшем случае,
+// а пятый день недели в феврале может не встречаться десятилетиями, поэтому для него
+// перебирается весь 400-летний цикл григорианского календаря
+func (r Monthly) searchMonths() int {
+	for _, w := range r.Weekdays {
+		if w.N == 5 || w.N == -5 {
+			return monthsInGregorianCycle
+		}
+	}
+	return monthsInLeapCycle
+}
+
+// dayInMonth возвращает наименьший день месяца из Days и Weekdays, больший after
+func (r Monthly) dayInMonth(year int, month time.Month, after int) (int, bool) {
+	last := daysIn(year, month)
+	best := 0
+	for _, d := range r.Days {
+		if d < 0 {
+			d = last + 1 + d
+		}
+		if d > after && d <= last && (best == 0 || d < best) {
+			best = d
+		}
+	}
+	for _, w := range r.Weekdays {
+		if d, ok := w.day(year, month); ok && d > after && (best == 0 || d < best) {
+			best = d
+		}
+	}
+	return best, best > 0
+}
+
+// possible проверяет, что хотя бы один день из Days существует в одном из месяцев Months
+// День недели с номером от -5 до 5 встречается в любом месяце хотя бы в некоторые годы
+func (r Monthly) possible() bool {
+	if len(r.Weekdays) > 0 {
+		return true
+	}
+	for _, d := range r.Days {
+		if d < 0 {
+			return true
+		}
+		for m := time.January; m <= time.December; m++ {
+			if (len(r.Months) == 0 || slices.Contains(r.Months, int(m))) && d <= daysIn(2024, m) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// after возвращает первую дату после now, дни месяца не зависят от начальной даты
+func (r Monthly) after(start, now time.Time) (time.Time, bool) {
+	return r.Next(later(start, now))
+}
+
+func (r Yearly) String() string {
+	return "y"
+}
+
+// Next возвращает ту же дату в следующем году после after
+func (r Yearly) Next(after time.Time) (time.Time, bool) {
+	year, month, d := after.Date()
+	year++
+	if month == time.February && d == 29 && !isLeap(year) {
+		return time.Date(year, time.March, 1, 0, 0, 0, 0, time.UTC), true
+	}
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC), true
+}
+
+// after возвращает первую дату после now, не перебирая годы по одному
+// Дата 29 февраля после первого же переноса становится 1 марта и дальше не меняется
+func (r Yearly) after(start, now time.Time) (time.Time, bool) {
+	year, month, d := start.Date()
+	if month == time.February && d == 29 {
+		month, d = time.March, 1
+	}
+	year = max(year+1, now.Year())
+	date := time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	if !AfterNow(date, now) {
+		date = date.AddDate(1, 0, 0)
+	}
+	return date, true
+}
+
+// monthsInLeapCycle - наибольшее число месяцев между двумя 29 февраля с учетом невисокосных 2100, 2200 и 2300 годов
+const monthsInLeapCycle = 12*8 + 1
+
+// monthsInGregorianCycle - число месяцев, через которое григорианский календарь повторяется вместе с днями недели
+const monthsInGregorianCycle = 12 * 400
+
+// isoWeekday возвращает день недели от 1 (понедельник) до 7 (воскресенье)
+func isoWeekday(t time.Time) int {
+	weekday := int(t.Weekday())
+	if weekday == 0 {
+		return 7
+	}
+	return weekday
+}
+
+// daysIn возвращает количество дней в месяце
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// daysBetween возвращает количество дней от from до to, обе даты - полночь UTC
+// Разность считается по секундам Unix: time.Duration переполняется на интервалах больше 292 лет
+func daysBetween(from, to time.Time) int {
+	return int((to.Unix() - from.Unix()) / (24 * 60 * 60))
+}
+
+// later возвращает более позднюю из двух дат
+func later(a, b time.Time) time.Time {
+	if AfterNow(a, b) {
+		return a
+	}
+	return b
+}
+
+// isLeap проверяет, что год високосный
+func isLeap(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
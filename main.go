@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"text/tabwriter"
+	_ "time/tzdata" // база часовых поясов IANA встраивается в программу, ее может не быть в образе Docker
 
 	"github.com/eOne007/final-project-yapr/pkg/api"
 	"github.com/eOne007/final-project-yapr/pkg/db"
@@ -19,33 +22,100 @@ func getPort() string {
 	return "7540"
 }
 
+// getDBConfig возвращает драйвер БД и строку подключения
+// драйвер определяется из переменной окружения TODO_DB_DRIVER (sqlite или postgres), по умолчанию sqlite
+// строка подключения берется из TODO_DB_DSN; для SQLite, если она не задана, используется путь из TODO_DBFILE
+// или файл из текущей директории, для PostgreSQL - стандартные переменные окружения PG*
+func getDBConfig() (string, string) {
+	driver := os.Getenv("TODO_DB_DRIVER")
+	if driver == "" {
+		driver = db.DriverSQLite
+	}
+	if dsn := os.Getenv("TODO_DB_DSN"); dsn != "" || driver != db.DriverSQLite {
+		return driver, dsn
+	}
+	if dbFile := os.Getenv("TODO_DBFILE"); dbFile != "" {
+		return driver, dbFile
+	}
+	return driver, "scheduler.db"
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate(os.Args[2:]); err != nil {
+			log.Printf("Ошибка: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
 	if err := run(); err != nil {
 		log.Printf("Ошибка: %v", err)
 		os.Exit(1)
 	}
 }
 func run() error {
-	// получаем путь к БД из переменной окружения TODO_DBFILE
-	// если переменнач не определена, используем файл из текущей директории
-	dbFile := os.Getenv("TODO_DBFILE") 
-	if dbFile == "" {
-    	dbFile = "scheduler.db"
-	}
-
-	if err := db.Init(dbFile); err != nil {
+	store, err := db.NewStore(getDBConfig())
+	if err != nil {
 		return fmt.Errorf("DB error: %w", err)
 	}
-	defer db.DB.Close() // гарантированное закрытие соединения с БД при завершении программы
-
-	api.Init()
+	defer store.Close() // гарантированное закрытие соединения с БД при завершении программы
+	api.StartTrashPurge(context.Background(), store)
 
-	http.Handle("/", http.FileServer(http.Dir("./web")))
+	mux := http.NewServeMux()
+	mux.Handle("/api/", api.NewServer(store))
+	mux.Handle("/", http.FileServer(http.Dir("./web")))
 
 	port := getPort()
 	log.Printf("Запуск сервера на порту %s...", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
+	if err := http.ListenAndServe(":"+port, mux); err != nil {
 		return fmt.Errorf("server error: %w", err)
 	}
 	return nil
+}
+
+// migrate выполняет команду управления миграциями схемы БД:
+// "migrate status" выводит список миграций, "migrate up" применяет недостающие
+func migrate(args []string) error {
+	command := "status"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	store, err := db.OpenStore(getDBConfig())
+	if err != nil {
+		return fmt.Errorf("DB error: %w", err)
+	}
+	defer store.Close()
+
+	switch command {
+	case "status":
+	case "up":
+		if err := store.Migrate(); err != nil {
+			return fmt.Errorf("DB migration error: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command: %s", command)
+	}
+
+	status, err := store.Status()
+	if err != nil {
+		return err
+	}
+	version := 0
+	for _, m := range status {
+		if m.Applied {
+			version = m.Version
+		}
+	}
+
+	fmt.Printf("Schema version: %d (latest: %d)\n", version, len(status))
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, m := range status {
+		state := "pending"
+		if m.Applied {
+			state = "applied"
+		}
+		fmt.Fprintf(tw, "%04d\t%s\t%s\n", m.Version, m.Name, state)
+	}
+	return tw.Flush()
 }
\ No newline at end of file
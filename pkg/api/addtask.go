@@ -2,33 +2,41 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/eOne007/final-project-yapr/internal/repeater"
 	"github.com/eOne007/final-project-yapr/pkg/db"
 )
 
+// TimeFormat - формат времени начала задачи, MaxDuration - наибольшая длительность задачи в минутах
+const (
+	TimeFormat  = "15:04"
+	MaxDuration = 7 * 24 * 60
+)
+
 // taskHandler - маршрутизатор для эндпойнта /task
 // Определяет метод запроса и вызывает соответствующий обработчик
-func taskHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) taskHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodPost:
-		addTaskHandler(w, r)
+		s.addTaskHandler(w, r)
 	case http.MethodGet:
-		getTaskHandler(w, r)
+		s.getTaskHandler(w, r)
 	case http.MethodPut:
-		updateTaskHandler(w, r)
+		s.updateTaskHandler(w, r)
 	case http.MethodDelete:
-		deleteTaskHandler(w, r)
+		s.deleteTaskHandler(w, r)
 	default:
 		writeJson(w, http.StatusMethodNotAllowed, db.Response{Error: "Method not allowed"})
 	}
 }
 
 // addTaskHandler обрабатывает POST-запрос на добавление новой задачи
-func addTaskHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) addTaskHandler(w http.ResponseWriter, r *http.Request) {
 	var task db.Task
 	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
 		writeJson(w, http.StatusBadRequest, db.Response{Error: "Incorrect JSON format"})
@@ -40,7 +48,28 @@ func addTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := checkDate(&task); err != nil {
+	if err := checkTime(&task); err != nil {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: err.Error()})
+		return
+	}
+
+	now, err := s.today(r, task.Timezone)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
+		return
+	}
+	// пустая или неверная дата не сдвигается по правилу, и календарь для нее не нужен
+	start, err := time.Parse(db.DateFormat, task.Date)
+	if err != nil {
+		start = now
+	}
+	cal, err := s.calendar(userID(r), start, now, task.Repeat)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
+		return
+	}
+
+	if err := checkDate(&task, cal, now); err != nil {
 		writeJson(w, http.StatusBadRequest, db.Response{Error: err.Error()})
 		return
 	}
@@ -49,7 +78,12 @@ func addTaskHandler(w http.ResponseWriter, r *http.Request) {
 		writeJson(w, http.StatusBadRequest, db.Response{Error: err.Error()})
 		return
 	}
-	id, err := db.AddTask(&task)
+
+	if err := checkEnd(&task); err != nil {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: err.Error()})
+		return
+	}
+	id, err := s.store.Add(userID(r), &task)
 		if err != nil {
 			writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database addition error"})
 			return
@@ -58,31 +92,34 @@ func addTaskHandler(w http.ResponseWriter, r *http.Request) {
 }	
 
 // getTaskHandler обрабатывает GET-запрос на получение задачи по id
-func getTaskHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) getTaskHandler(w http.ResponseWriter, r *http.Request) {
 	id := r.URL.Query().Get("id")
 		if id == "" {
 			writeJson(w, http.StatusBadRequest, db.Response{Error: "id is required"})
 			return
 		}
-	task, err := db.GetTask(id)
+	task, err := s.store.Get(userID(r), id)
 		if err != nil {
-			if err.Error() == "task not found"{
+			if errors.Is(err, db.ErrTaskNotFound){
 				writeJson(w, http.StatusNotFound, db.Response{Error: err.Error()})
 			} else {
 				writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
 			}
 			return
 		}
+	describeRepeat(r, task)
 	writeJson(w, http.StatusOK, task)
 }
 
 // updateTaskHandler обрабатывает PUT-запрос на обновление существующей задачи
-func updateTaskHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) updateTaskHandler(w http.ResponseWriter, r *http.Request) {
 	decoder := json.NewDecoder(r.Body)
     decoder.UseNumber()
+	var body json.RawMessage
 	var task db.Task
+	var fields map[string]json.RawMessage
 
-	if err := decoder.Decode(&task); err != nil {
+	if err := decoder.Decode(&body); err != nil || json.Unmarshal(body, &task) != nil || json.Unmarshal(body, &fields) != nil {
 		writeJson(w, http.StatusBadRequest, db.Response{Error: "Incorrect JSON format"})
 		return
 	}
@@ -107,76 +144,195 @@ func updateTaskHandler(w http.ResponseWriter, r *http.Request) {
     	return
 	}
 
+	if err := s.keepStored(userID(r), &task, fields); err != nil {
+		if errors.Is(err, db.ErrTaskNotFound) {
+			writeJson(w, http.StatusNotFound, db.Response{Error: err.Error()})
+		} else {
+			writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
+		}
+		return
+	}
+
+	if err := checkTime(&task); err != nil {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: err.Error()})
+		return
+	}
+
 	if err := checkRepeat(&task); err != nil {
-		writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database update error"})
+		writeJson(w, http.StatusBadRequest, db.Response{Error: err.Error()})
+		return
+	}
+
+	if err := checkEnd(&task); err != nil {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: err.Error()})
+		return
+	}
+
+	if err := checkSnooze(&task); err != nil {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: err.Error()})
 		return
 	}
-	if err := db.UpdateTask(&task); err != nil {
+	if err := s.store.Update(userID(r), &task); err != nil {
         writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database update error"})
         return
 	}
 	writeJson(w, http.StatusOK, map[string]string{})
 }
 
-// deleteTaskHandler обрабатывает DELETE-запрос на удаление существующей задачи
-func deleteTaskHandler(w http.ResponseWriter, r *http.Request) {
+// keptFields - поля задачи, которые при обновлении сохраняют значения из БД, если их нет в запросе,
+// чтобы клиент, который не знает об этих полях, не сбрасывал их
+// Сбросить такое поле можно, передав его с пустым значением
+var keptFields = []string{"until", "remaining", "time", "duration", "timezone", "snoozed_from"}
+
+// keepStored заполняет поля из keptFields, которых нет в запросе fields, значениями задачи из БД
+func (s *Server) keepStored(userID int64, task *db.Task, fields map[string]json.RawMessage) error {
+	absent := func(name string) bool {
+		_, ok := fields[name]
+		return !ok
+	}
+	if !slices.ContainsFunc(keptFields, absent) {
+		return nil
+	}
+	stored, err := s.store.Get(userID, task.ID)
+	if err != nil {
+		return err
+	}
+	if absent("until") {
+		task.Until = stored.Until
+	}
+	if absent("remaining") {
+		task.Remaining = stored.Remaining
+	}
+	if absent("time") {
+		task.Time = stored.Time
+	}
+	// длительность без времени начала не задается: если время сброшено, сбрасывается и она
+	if absent("duration") && task.Time != "" {
+		task.Duration = stored.Duration
+	}
+	if absent("timezone") {
+		task.Timezone = stored.Timezone
+	}
+	if absent("snoozed_from") {
+		task.SnoozedFrom = stored.SnoozedFrom
+	}
+	return nil
+}
+
+// deleteTaskHandler обрабатывает DELETE-запрос на перемещение существующей задачи в корзину,
+// в ответе возвращается токен для отмены удаления
+func (s *Server) deleteTaskHandler(w http.ResponseWriter, r *http.Request) {
 	id := r.URL.Query().Get("id")
 	if id == "" {
 		writeJson(w, http.StatusBadRequest, db.Response{Error: "id is required"})
 		return
 	}
 
-	if err := db.DeleteTask(id); err != nil {
-		if err.Error() == "task not found"{
+	undo, err := s.newUndo()
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, db.Response{Error: err.Error()})
+		return
+	}
+	if err := s.store.Delete(userID(r), id, undo); err != nil {
+		if errors.Is(err, db.ErrTaskNotFound){
 			writeJson(w, http.StatusNotFound, db.Response{Error: err.Error()})
 		} else {
 			writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
 		}
 		return
 	}
-	writeJson(w, http.StatusOK, map[string]string{})
+	writeJson(w, http.StatusOK, UndoResp{UndoToken: undo.Token, UndoExpiresAt: undo.ExpiresAt})
+}
+
+// taskDoneHandler обрабатывает завершение выполненной задачи, выполнение записывается в журнал,
+// в ответе возвращается токен для отмены выполнения
+func (s *Server) taskDoneHandler(w http.ResponseWriter, r *http.Request) {
+	s.advanceTask(w, r, db.CompletionDone)
 }
 
-// taskDoneHandler обрабатывает завершение выполненной задачи
-func taskDoneHandler(w http.ResponseWriter, r *http.Request) {
+// advanceTask отмечает текущее повторение задачи как выполненное или пропущенное (kind) и записывает отметку в журнал:
+// разовая задача и задача, серия повторений которой закончилась, удаляются, остальные переносятся на следующую дату
+// Следующая дата перенесенного повторения считается от его исходной даты, поэтому серия не сдвигается
+func (s *Server) advanceTask(w http.ResponseWriter, r *http.Request, kind string) {
 	if r.Method != http.MethodPost {
 		writeJson(w, http.StatusMethodNotAllowed, db.Response{Error: "Method not allowed"})
+		return
 	}
 
 	id := r.URL.Query().Get("id")
 	if id == "" {
 		writeJson(w, http.StatusBadRequest, db.Response{Error: "id is required"})
+		return
 	}
 
-	task, err := db.GetTask(id)
+	task, err := s.store.Get(userID(r), id)
 	if err != nil {
-		if err.Error() == "task not found"{
+		if errors.Is(err, db.ErrTaskNotFound){
 			writeJson(w, http.StatusNotFound, db.Response{Error: err.Error()})
 		} else {
 			writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
 		}
 		return
 	}
+	if kind == db.CompletionSkipped && task.Repeat == "" {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: "only recurring tasks can be skipped"})
+		return
+	}
 
-	if task.Repeat == "" {
-		if err := db.DeleteTask(id); err != nil {
-			writeJson(w, http.StatusInternalServerError, db.Response{Error: err.Error()})
+	var nextDate string
+	if task.Repeat != "" {
+		now, err := s.today(r, task.Timezone)
+		if err != nil {
+			writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
+			return
+		}
+		start, err := time.Parse(db.DateFormat, seriesDate(task))
+		if err != nil {
+			start = now
+		}
+		cal, err := s.calendar(userID(r), start, now, task.Repeat)
+		if err != nil {
+			writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
 			return
 		}
-	} else {
-		now := time.Now()
-		nextDate, err := repeater.NextDate(now, task.Date, task.Repeat)
+		nextDate, err = repeater.NextDateIn(cal, now, seriesDate(task), task.Repeat)
 		if err != nil {
 			writeJson(w, http.StatusBadRequest, db.Response{Error: fmt.Sprintf("error calculating next date: %v", err)})
 			return
 		}
+	}
+
+	completion := &db.Completion{TaskID: task.ID, Kind: kind, Title: task.Title, Date: task.Date,
+		DoneAt: time.Now().UTC().Format(db.TimestampFormat)}
+	var next *db.Task
+	if task.Repeat != "" && !lastOccurrence(task, nextDate) {
+		moved := *task
+		moved.Date, moved.SnoozedFrom = nextDate, ""
+		if moved.Remaining > 0 {
+			moved.Remaining--
+		}
+		next, completion.NextDate = &moved, nextDate
+	}
+	s.complete(w, r, completion, next)
+}
 
-		if err = db.UpdateDate(nextDate, id); err != nil {
+// complete сохраняет отметку журнала вместе с изменением задачи next (nil - задача удаляется),
+// в ответе возвращается токен для отмены операции
+func (s *Server) complete(w http.ResponseWriter, r *http.Request, completion *db.Completion, next *db.Task) {
+	undo, err := s.newUndo()
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, db.Response{Error: err.Error()})
+		return
+	}
+	if err := s.store.Complete(userID(r), completion, next, undo); err != nil {
+		if errors.Is(err, db.ErrTaskNotFound) {
+			writeJson(w, http.StatusNotFound, db.Response{Error: err.Error()})
+		} else {
 			writeJson(w, http.StatusInternalServerError, db.Response{Error: err.Error()})
-			return
 		}
+		return
 	}
-	writeJson(w, http.StatusOK, map[string]string{})
+	writeJson(w, http.StatusOK, UndoResp{UndoToken: undo.Token, UndoExpiresAt: undo.ExpiresAt})
 }
 
 // writeJson — функция для отправки ответа в формате JSON
@@ -197,9 +353,8 @@ func writeJson(w http.ResponseWriter, codeStatus int, data interface{}) {
 // - без повтора - устанавдивается текущая
 // - с правилом повторения - вычисляется следующая джата согласно правила
 // 3. Если дата больше или равна сегодняшней - остается, как есть 
-func checkDate(task *db.Task) error {
-	now := time.Now()
-
+// Рабочие дни для правил b и bm считаются по календарю cal, now - текущая дата в часовом поясе задачи
+func checkDate(task *db.Task, cal repeater.Calendar, now time.Time) error {
 	if task.Date == now.Format(db.DateFormat) {
 		return nil
 	}
@@ -218,25 +373,119 @@ func checkDate(task *db.Task) error {
 		if len(task.Repeat) == 0 {
 			task.Date = now.Format(db.DateFormat)
 		} else {
-			nextDate, err := repeater.NextDate(now, task.Date, task.Repeat)
+			nextDate, err := repeater.NextDateIn(cal, now, task.Date, task.Repeat)
 			if err != nil {
 				return fmt.Errorf("incorrect repeat rule: %w", err)
 			}
+			if err := skipCount(task, cal, t, nextDate); err != nil {
+				return err
+			}
 			task.Date = nextDate
 		}
 	}
 	return nil
 }
 
-// checkRepeat проверка правила повторения
+// skipCount учитывает даты правила RRULE с COUNT, пропущенные при переносе даты start в прошлом на nextDate:
+// они входят в COUNT, поэтому число оставшихся выполнений задачи уменьшается на их количество
+// Если число оставшихся выполнений задано явно, оно не меняется
+func skipCount(task *db.Task, cal repeater.Calendar, start time.Time, nextDate string) error {
+	rule, err := repeater.Parse(task.Repeat)
+	if err != nil {
+		return fmt.Errorf("incorrect repeat rule: %w", err)
+	}
+	rrule, ok := rule.(repeater.RRule)
+	if !ok || rrule.Count == 0 || task.Remaining != 0 {
+		return nil
+	}
+	skipped := 1
+	for date, err := range repeater.DatesIn(cal, start, task.Repeat, start) {
+		if err != nil {
+			return fmt.Errorf("incorrect repeat rule: %w", err)
+		}
+		if date.Format(db.DateFormat) >= nextDate {
+			break
+		}
+		skipped++
+	}
+	task.Remaining = rrule.Count - skipped
+	return nil
+}
+
+// checkTime проверка времени начала, длительности и часового пояса задачи
+// Время записывается как 15:04, длительность в минутах задается только вместе со временем начала
+func checkTime(task *db.Task) error {
+	if task.Time != "" {
+		t, err := time.Parse(TimeFormat, task.Time)
+		if err != nil {
+			return errors.New("'time' must be in HH:MM format")
+		}
+		task.Time = t.Format(TimeFormat)
+	}
+	if task.Duration < 0 || task.Duration > MaxDuration {
+		return fmt.Errorf("'duration' must be from 0 to %d minutes", MaxDuration)
+	}
+	if task.Duration > 0 && task.Time == "" {
+		return errors.New("'duration' requires a start time")
+	}
+	if task.Timezone != "" {
+		if _, err := loadLocation(task.Timezone); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkRepeat проверка правила повторения, правило сохраняется в канонической записи
+// COUNT и UNTIL правила RRULE переносятся в число оставшихся выполнений и дату окончания задачи,
+// чтобы серия заканчивалась при отметках о выполнении
 func checkRepeat(task *db.Task) error {
 	if task.Repeat == "" {
 		return nil
 	}
-	_, err := repeater.NextDate(time.Now(), task.Date, task.Repeat)
+	rule, err := repeater.Parse(task.Repeat)
 	if err != nil {
-		return fmt.Errorf("incorrect repeat rule: %w", err)
+		return err
 	}
+	if rrule, ok := rule.(repeater.RRule); ok {
+		if rrule.Count > 0 && task.Remaining == 0 {
+			task.Remaining = rrule.Count
+		}
+		if !rrule.Until.IsZero() && task.Until == "" {
+			task.Until = rrule.Until.Format(db.DateFormat)
+		}
+		rrule.Count, rrule.Until = 0, time.Time{}
+		rule = rrule
+	}
+	task.Repeat = rule.String()
 	return nil
 }
 
+// checkEnd проверка условий окончания повторений: даты окончания и числа оставшихся выполнений
+// Условия допустимы только для повторяющихся задач, дата окончания не может быть раньше даты задачи
+func checkEnd(task *db.Task) error {
+	if task.Until == "" && task.Remaining == 0 {
+		return nil
+	}
+	if task.Repeat == "" {
+		return errors.New("'until' and 'remaining' require a repeat rule")
+	}
+	if task.Remaining < 0 {
+		return errors.New("'remaining' must not be negative")
+	}
+	if task.Until != "" {
+		if _, err := time.Parse(db.DateFormat, task.Until); err != nil {
+			return fmt.Errorf("incorrect until date format: %w", err)
+		}
+		if task.Until < task.Date {
+			return errors.New("'until' date is before the task date")
+		}
+	}
+	return nil
+}
+
+// lastOccurrence проверяет, что выполнение задачи завершает серию повторений:
+// выполнений больше не осталось или следующая дата позже даты окончания
+func lastOccurrence(task *db.Task, nextDate string) bool {
+	return task.Remaining == 1 || task.Until != "" && nextDate > task.Until
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/eOne007/final-project-yapr/internal/repeater"
+	"github.com/eOne007/final-project-yapr/pkg/db"
+)
+
+// AgendaDays - период повестки по умолчанию, MaxAgendaDays - наибольший допустимый период
+const (
+	AgendaDays    = 14
+	MaxAgendaDays = 366
+)
+
+// AgendaDay - задачи на один день повестки
+type AgendaDay struct {
+	Date  string     `json:"date"`
+	Tasks []*db.Task `json:"tasks"`
+}
+
+// AgendaResp - структура ответа повестки, дни без задач в нее не включаются
+type AgendaResp struct {
+	From string      `json:"from"`
+	To   string      `json:"to"`
+	Days []AgendaDay `json:"days"`
+}
+
+// agendaHandler обрабатывает GET-запрос повестки за период from - to включительно
+// Повторяющиеся задачи разворачиваются во все даты повторения внутри периода,
+// разовые задачи попадают в повестку на свою дату. По умолчанию период - две недели начиная с сегодня
+func (s *Server) agendaHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJson(w, http.StatusMethodNotAllowed, ErrorResp{Error: "Method not allowed"})
+		return
+	}
+
+	now, err := s.today(r, "")
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, ErrorResp{Error: err.Error()})
+		return
+	}
+	from, to, err := parsePeriod(r, now, AgendaDays, MaxAgendaDays)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, ErrorResp{Error: err.Error()})
+		return
+	}
+	fromDate, toDate := from.Format(db.DateFormat), to.Format(db.DateFormat)
+
+	// повторяющиеся задачи могут повториться в периоде, даже если их дата раньше его начала
+	filter := db.And(
+		&db.Filter{Field: db.FieldTo, Value: toDate},
+		&db.Filter{Op: db.FilterOr, Children: []*db.Filter{
+			{Field: db.FieldRepeat, Value: db.RepeatAny},
+			{Field: db.FieldFrom, Value: fromDate},
+		}},
+	)
+	tasks, err := s.findAll(userID(r), filter)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, ErrorResp{Error: err.Error()})
+		return
+	}
+
+	// праздники нужны только правилам по рабочим дням: от самой ранней из дат их серий до конца периода
+	start, repeats := from, []string{}
+	for _, task := range tasks {
+		if !usesCalendar(task.Repeat) {
+			continue
+		}
+		repeats = append(repeats, task.Repeat)
+		if date, err := time.Parse(db.DateFormat, seriesDate(task)); err == nil && date.Before(start) {
+			start = date
+		}
+	}
+	cal, err := s.calendar(userID(r), start, to, repeats...)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, ErrorResp{Error: err.Error()})
+		return
+	}
+
+	days := make(map[string][]*db.Task)
+	for _, task := range tasks {
+		for _, date := range occurrences(task, from, to, cal) {
+			occurrence := *task
+			occurrence.Date = date
+			days[date] = append(days[date], &occurrence)
+		}
+	}
+
+	resp := AgendaResp{From: fromDate, To: toDate, Days: []AgendaDay{}}
+	for date, list := range days {
+		// задачи на весь день идут первыми, остальные - по времени начала
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Time < list[j].Time })
+		resp.Days = append(resp.Days, AgendaDay{Date: date, Tasks: list})
+	}
+	sort.Slice(resp.Days, func(i, j int) bool { return resp.Days[i].Date < resp.Days[j].Date })
+	writeJson(w, http.StatusOK, resp)
+}
+
+// occurrences возвращает даты задачи в периоде from - to включительно
+// Дата задачи - ее ближайшее выполнение, следующие даты вычисляются по правилу повторения
+// от исходной даты этого выполнения, если оно перенесено, и не выходят за дату окончания
+// и число оставшихся выполнений задачи
+// Рабочие дни для правил b и bm считаются по календарю cal
+func occurrences(task *db.Task, from, to time.Time, cal repeater.Calendar) []string {
+	fromDate, toDate := from.Format(db.DateFormat), to.Format(db.DateFormat)
+	date := task.Date
+	if task.Repeat == "" {
+		if date >= fromDate && date <= toDate {
+			return []string{date}
+		}
+		return nil
+	}
+
+	start, err := time.Parse(db.DateFormat, seriesDate(task))
+	if err != nil {
+		return nil
+	}
+	last := toDate
+	if task.Until != "" && task.Until < last {
+		last = task.Until
+	}
+	var dates []string
+	if date >= fromDate && date <= last {
+		dates = append(dates, date)
+	}
+	// с ограничением числа выполнений даты отсчитываются от даты задачи, иначе - сразу от начала периода
+	after := start
+	if seriesDate(task) < fromDate && task.Remaining == 0 {
+		after = from.AddDate(0, 0, -1)
+	}
+	count := 1
+	for next, err := range repeater.DatesIn(cal, start, task.Repeat, after) {
+		if err != nil || task.Remaining > 0 && count >= task.Remaining {
+			break
+		}
+		nextDate := next.Format(db.DateFormat)
+		if nextDate > last {
+			break
+		}
+		count++
+		if nextDate >= fromDate {
+			dates = append(dates, nextDate)
+		}
+	}
+	return dates
+}
+
+// parsePeriod получает из запроса период from - to включительно
+// Без from период начинается сегодня, без to длится days дней, период не может быть длиннее maxDays дней
+func parsePeriod(r *http.Request, now time.Time, days, maxDays int) (time.Time, time.Time, error) {
+	parse := func(name string, def time.Time) (time.Time, error) {
+		v := r.URL.Query().Get(name)
+		if v == "" {
+			return def, nil
+		}
+		date, err := parseDate(v, now)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%s: %w", name, err)
+		}
+		return time.Parse(db.DateFormat, date)
+	}
+
+	today, _ := time.Parse(db.DateFormat, now.Format(db.DateFormat))
+	from, err := parse("from", today)
+	if err != nil {
+		return from, from, err
+	}
+	to, err := parse("to", from.AddDate(0, 0, days-1))
+	if err != nil {
+		return from, to, err
+	}
+	if to.Before(from) {
+		return from, to, fmt.Errorf("to must not be earlier than from")
+	}
+	if to.After(from.AddDate(0, 0, maxDays-1)) {
+		return from, to, fmt.Errorf("period must not be longer than %d days", maxDays)
+	}
+	return from, to, nil
+}
+
+// findAll получает все задачи пользователя, подходящие под условие, постранично
+func (s *Server) findAll(userID int64, filter *db.Filter) ([]*db.Task, error) {
+	var tasks []*db.Task
+	page := db.Page{Limit: MaxTasksLimit}
+	for {
+		result, err := s.store.Find(userID, page, filter)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, result.Tasks...)
+		if result.Next == nil {
+			return tasks, nil
+		}
+		page.After = result.Next
+	}
+}
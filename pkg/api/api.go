@@ -2,12 +2,54 @@ package api
 
 import (
 	"net/http"
+	"sync"
+	"time"
+
+	"github.com/eOne007/final-project-yapr/pkg/db"
 )
 
-// Init регистрирует все API-обработчики
-func Init() {
-	http.HandleFunc("/api/nextdate", nextDayHandler)
-	http.HandleFunc("/api/task", taskHandler)
-	http.HandleFunc("/api/tasks", tasksHandler)
-	http.HandleFunc("/api/task/done", taskDoneHandler)
+// Server - обработчик API планировщика, работающий с переданным хранилищем
+// loc - часовой пояс сервера, в котором считаются даты пользователей без своего часового пояса,
+// undoWindow - срок действия токенов отмены удаления и выполнения задач
+type Server struct {
+	store      db.Store
+	mux        *http.ServeMux
+	loc        *time.Location
+	undoWindow time.Duration
+
+	// ключ подписи токенов, полученный из хранилища, если он не задан в TODO_SECRET
+	keyMu sync.Mutex
+	key   []byte
+}
+
+// NewServer создает обработчик API и регистрирует все API-обработчики
+func NewServer(store db.Store) http.Handler {
+	s := &Server{store: store, mux: http.NewServeMux(), loc: serverLocation(), undoWindow: undoWindow()}
+
+	s.mux.HandleFunc("/api/signin", s.signinHandler)
+	s.mux.HandleFunc("/api/signup", s.signupHandler)
+	s.mux.HandleFunc("/api/nextdate", s.auth(s.nextDayHandler))
+	s.mux.HandleFunc("/api/nextdates", s.auth(s.nextDatesHandler))
+	s.mux.HandleFunc("/api/rrule", s.auth(rruleHandler))
+	s.mux.HandleFunc("/api/task", s.auth(s.taskHandler))
+	s.mux.HandleFunc("/api/tasks", s.auth(s.tasksHandler))
+	s.mux.HandleFunc("/api/task/done", s.auth(s.taskDoneHandler))
+	s.mux.HandleFunc("/api/task/skip", s.auth(s.taskSkipHandler))
+	s.mux.HandleFunc("/api/task/snooze", s.auth(s.taskSnoozeHandler))
+	s.mux.HandleFunc("/api/task/history", s.auth(s.taskHistoryHandler))
+	s.mux.HandleFunc("/api/completions", s.auth(s.completionsHandler))
+	s.mux.HandleFunc("/api/undo", s.auth(s.undoHandler))
+	s.mux.HandleFunc("/api/trash", s.auth(s.trashHandler))
+	s.mux.HandleFunc("/api/trash/restore", s.auth(s.restoreTaskHandler))
+	s.mux.HandleFunc("/api/agenda", s.auth(s.agendaHandler))
+	s.mux.HandleFunc("/api/tokens", s.auth(s.apiTokensHandler))
+	s.mux.HandleFunc("/api/holidays", s.auth(s.holidaysHandler))
+	s.mux.HandleFunc("/api/holidays/import", s.auth(s.importHolidaysHandler))
+	s.mux.HandleFunc("/api/settings", s.auth(s.settingsHandler))
+	return s
+}
+
+// ServeHTTP передает запрос обработчику, зарегистрированному для его пути
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
 }
\ No newline at end of file
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/eOne007/final-project-yapr/pkg/db"
+)
+
+// apiTokenPrefix - префикс, по которому персональные API-токены отличаются от JWT-токенов
+const apiTokenPrefix = "yapr_"
+
+// TokensResp — структура ответа для списка API-токенов
+type TokensResp struct {
+	Tokens []*db.APIToken `json:"tokens"`
+}
+
+// NewTokenResp — структура ответа на создание API-токена
+// значение токена возвращается только один раз, в БД хранится его хэш
+type NewTokenResp struct {
+	*db.APIToken
+	Token string `json:"token"`
+}
+
+// apiTokensHandler - маршрутизатор для эндпойнта /tokens
+// Управлять токенами можно только после входа по паролю, но не с помощью самого API-токена
+func (s *Server) apiTokensHandler(w http.ResponseWriter, r *http.Request) {
+	if viaAPIToken(r) {
+		writeJson(w, http.StatusForbidden, db.Response{Error: "API tokens cannot manage tokens"})
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		s.listTokensHandler(w, r)
+	case http.MethodPost:
+		s.addTokenHandler(w, r)
+	case http.MethodPut:
+		s.renameTokenHandler(w, r)
+	case http.MethodDelete:
+		s.deleteTokenHandler(w, r)
+	default:
+		writeJson(w, http.StatusMethodNotAllowed, db.Response{Error: "Method not allowed"})
+	}
+}
+
+// listTokensHandler обрабатывает GET-запрос на получение списка API-токенов пользователя
+func (s *Server) listTokensHandler(w http.ResponseWriter, r *http.Request) {
+	tokens, err := s.store.Tokens(userID(r))
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, db.Response{Error: err.Error()})
+		return
+	}
+	if tokens == nil {
+		tokens = []*db.APIToken{}
+	}
+	writeJson(w, http.StatusOK, TokensResp{Tokens: tokens})
+}
+
+// addTokenHandler обрабатывает POST-запрос на создание API-токена
+// Срок действия expires_at указывается в формате RFC 3339 и может быть пустым
+func (s *Server) addTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var token db.APIToken
+	if err := json.NewDecoder(r.Body).Decode(&token); err != nil {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: "Incorrect JSON format"})
+		return
+	}
+
+	token.Name = strings.TrimSpace(token.Name)
+	if len(token.Name) > 128 {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: "'Name' field is too long"})
+		return
+	}
+
+	now := time.Now().UTC()
+	if token.ExpiresAt != "" {
+		expires, err := time.Parse(time.RFC3339, token.ExpiresAt)
+		if err != nil {
+			writeJson(w, http.StatusBadRequest, db.Response{Error: "incorrect expires_at format"})
+			return
+		}
+		if !expires.After(now) {
+			writeJson(w, http.StatusBadRequest, db.Response{Error: "expires_at must be in the future"})
+			return
+		}
+		token.ExpiresAt = expires.UTC().Format(time.RFC3339)
+	}
+	token.CreatedAt = now.Format(time.RFC3339)
+	token.LastUsedAt = ""
+
+	value, err := newAPIToken()
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, db.Response{Error: "Token creation error"})
+		return
+	}
+	id, err := s.store.AddToken(userID(r), &token, tokenHash(value))
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database addition error"})
+		return
+	}
+	token.ID = fmt.Sprintf("%d", id)
+	writeJson(w, http.StatusCreated, NewTokenResp{APIToken: &token, Token: value})
+}
+
+// renameTokenHandler обрабатывает PUT-запрос на изменение названия API-токена
+func (s *Server) renameTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var token db.APIToken
+	if err := json.NewDecoder(r.Body).Decode(&token); err != nil {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: "Incorrect JSON format"})
+		return
+	}
+	if token.ID == "" {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: "'Id' field cannot be empty"})
+		return
+	}
+	token.Name = strings.TrimSpace(token.Name)
+	if len(token.Name) > 128 {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: "'Name' field is too long"})
+		return
+	}
+
+	if err := s.store.RenameToken(userID(r), token.ID, token.Name); err != nil {
+		if errors.Is(err, db.ErrTokenNotFound) {
+			writeJson(w, http.StatusNotFound, db.Response{Error: err.Error()})
+		} else {
+			writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database update error"})
+		}
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]string{})
+}
+
+// deleteTokenHandler обрабатывает DELETE-запрос на отзыв API-токена
+func (s *Server) deleteTokenHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: "id is required"})
+		return
+	}
+
+	if err := s.store.DeleteToken(userID(r), id); err != nil {
+		if errors.Is(err, db.ErrTokenNotFound) {
+			writeJson(w, http.StatusNotFound, db.Response{Error: err.Error()})
+		} else {
+			writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
+		}
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]string{})
+}
+
+// newAPIToken генерирует случайное значение API-токена
+func newAPIToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return apiTokenPrefix + hex.EncodeToString(buf), nil
+}
+
+// checkAPIToken проверяет API-токен по его хэшу и сроку действия, отмечает время использования
+func (s *Server) checkAPIToken(value string) (*db.APIToken, error) {
+	token, err := s.store.TokenByHash(tokenHash(value))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if token.Expired(now) {
+		return nil, fmt.Errorf("token expired")
+	}
+	if err := s.store.TouchToken(token.ID, now); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
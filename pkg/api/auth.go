@@ -0,0 +1,376 @@
+package api
+
+import (
+	"context"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eOne007/final-project-yapr/pkg/db"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// tokenTTL - время жизни выданного токена, совпадает со сроком жизни cookie в веб-интерфейсе
+const tokenTTL = 8 * time.Hour
+
+// параметры хэширования паролей пользователей
+const (
+	pbkdf2Iterations = 100000
+	pbkdf2SaltLen    = 16
+	pbkdf2KeyLen     = 32
+)
+
+// ctxKey - тип ключей контекста запроса, используемых пакетом api
+type ctxKey int
+
+// ключи контекста, под которыми middleware сохраняет id текущего пользователя
+// и признак аутентификации персональным API-токеном
+const (
+	userKey ctxKey = iota
+	apiTokenKey
+)
+
+// SigninReq — структура запроса на аутентификацию и регистрацию
+// Если логин не указан, пароль сверяется с TODO_PASSWORD
+type SigninReq struct {
+	Login    string `json:"login,omitempty"`
+	Password string `json:"password"`
+}
+
+// SigninResp — структура ответа с выданным токеном
+type SigninResp struct {
+	Token string `json:"token"`
+}
+
+// getPassword возвращает пароль из переменной окружения TODO_PASSWORD
+// пустая строка означает, что аутентификация отключена
+func getPassword() string {
+	return os.Getenv("TODO_PASSWORD")
+}
+
+// signupOpen сообщает, открыта ли регистрация для всех: переменная окружения TODO_SIGNUP со значением on
+// открывает ее, off - закрывает; по умолчанию регистрация открыта, только если пароль TODO_PASSWORD не задан
+func signupOpen() bool {
+	switch strings.ToLower(os.Getenv("TODO_SIGNUP")) {
+	case "on":
+		return true
+	case "off":
+		return false
+	}
+	return getPassword() == ""
+}
+
+// secretKeyName - имя ключа подписи токенов в хранилище
+const secretKeyName = "jwt"
+
+// secretKey возвращает ключ подписи токенов из переменной окружения TODO_SECRET
+// Если переменная не задана, используется случайный ключ, который создается при первом запуске и хранится в БД,
+// поэтому выданные токены остаются действительными после перезапуска сервера
+func (s *Server) secretKey() ([]byte, error) {
+	if secret := os.Getenv("TODO_SECRET"); secret != "" {
+		return []byte(secret), nil
+	}
+	s.keyMu.Lock()
+	defer s.keyMu.Unlock()
+	if s.key == nil {
+		value, err := s.store.ServerKey(secretKeyName, func() (string, error) {
+			key := make([]byte, 32)
+			if _, err := rand.Read(key); err != nil {
+				return "", err
+			}
+			return hex.EncodeToString(key), nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error getting secret key: %w", err)
+		}
+		s.key = []byte(value)
+	}
+	return s.key, nil
+}
+
+// tokenHash возвращает хэш секрета, который сохраняется в токене
+// при смене пароля хэш меняется и ранее выданные токены становятся недействительными
+func tokenHash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashPassword возвращает хэш пароля пользователя для хранения в БД
+// в формате pbkdf2-sha256$<итерации>$<соль>$<хэш>
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, pbkdf2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, pbkdf2Iterations, pbkdf2KeyLen)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("pbkdf2-sha256$%d$%s$%s", pbkdf2Iterations,
+		hex.EncodeToString(salt), hex.EncodeToString(key)), nil
+}
+
+// dummyHash - хэш, с которым сверяется пароль при входе по неизвестному логину
+var dummyHash = fmt.Sprintf("pbkdf2-sha256$%d$%s$%s", pbkdf2Iterations,
+	strings.Repeat("00", pbkdf2SaltLen), strings.Repeat("00", pbkdf2KeyLen))
+
+// checkPassword сверяет пароль с хэшем, сохраненным в БД
+func checkPassword(hash, password string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2-sha256" {
+		return false
+	}
+	iter, err := strconv.Atoi(parts[1])
+	if err != nil || iter < 1 {
+		return false
+	}
+	salt, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	want, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, iter, len(want))
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(key, want) == 1
+}
+
+// signinHandler обрабатывает POST-запрос на аутентификацию
+// По логину и паролю выполняется вход пользователя, по одному паролю - вход в общий список задач
+// При успешной проверке возвращает подписанный JWT-токен
+func (s *Server) signinHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJson(w, http.StatusMethodNotAllowed, db.Response{Error: "Method not allowed"})
+		return
+	}
+
+	var req SigninReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: "Incorrect JSON format"})
+		return
+	}
+
+	var (
+		uid    int64
+		secret string
+	)
+	if req.Login != "" {
+		user, err := s.store.GetUserByLogin(req.Login)
+		hash := dummyHash
+		if err == nil {
+			hash = user.PasswordHash
+		}
+		// пароль проверяется и для неизвестного логина, чтобы по времени ответа нельзя было узнать, какие логины есть
+		if !checkPassword(hash, req.Password) || err != nil {
+			writeJson(w, http.StatusUnauthorized, db.Response{Error: "Incorrect login or password"})
+			return
+		}
+		uid, secret = user.ID, user.PasswordHash
+	} else {
+		password := getPassword()
+		if password == "" {
+			writeJson(w, http.StatusBadRequest, db.Response{Error: "Authentication is disabled"})
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(req.Password), []byte(password)) != 1 {
+			writeJson(w, http.StatusUnauthorized, db.Response{Error: "Incorrect password"})
+			return
+		}
+		secret = password
+	}
+
+	token, err := s.newToken(uid, secret)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, db.Response{Error: "Token creation error"})
+		return
+	}
+	writeJson(w, http.StatusOK, SigninResp{Token: token})
+}
+
+// signupHandler обрабатывает POST-запрос на регистрацию нового пользователя
+// Возвращает токен, чтобы пользователь мог сразу работать со своими задачами
+// Если регистрация закрыта, зарегистрировать пользователя может только владелец пароля TODO_PASSWORD
+// с токеном общего списка задач
+func (s *Server) signupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJson(w, http.StatusMethodNotAllowed, db.Response{Error: "Method not allowed"})
+		return
+	}
+	if !signupOpen() && !s.isOwner(r) {
+		writeJson(w, http.StatusForbidden, db.Response{Error: "Registration is closed"})
+		return
+	}
+
+	var req SigninReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: "Incorrect JSON format"})
+		return
+	}
+
+	req.Login = strings.TrimSpace(req.Login)
+	if req.Login == "" || len(req.Login) > 64 {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: "'Login' field must be from 1 to 64 characters"})
+		return
+	}
+	if req.Password == "" {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: "'Password' field cannot be empty"})
+		return
+	}
+
+	hash, err := hashPassword(req.Password)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, db.Response{Error: "Password hashing error"})
+		return
+	}
+	uid, err := s.store.AddUser(req.Login, hash)
+	if err != nil {
+		if errors.Is(err, db.ErrUserExists) {
+			writeJson(w, http.StatusConflict, db.Response{Error: err.Error()})
+		} else {
+			writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database addition error"})
+		}
+		return
+	}
+
+	token, err := s.newToken(uid, hash)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, db.Response{Error: "Token creation error"})
+		return
+	}
+	writeJson(w, http.StatusCreated, SigninResp{Token: token})
+}
+
+// newToken создает JWT-токен пользователя, содержащий хэш его секрета
+// для общего списка задач (uid = 0) секретом служит пароль из TODO_PASSWORD
+func (s *Server) newToken(uid int64, secret string) (string, error) {
+	key, err := s.secretKey()
+	if err != nil {
+		return "", err
+	}
+	claims := jwt.MapClaims{
+		"uid":  uid,
+		"hash": tokenHash(secret),
+		"exp":  time.Now().Add(tokenTTL).Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+}
+
+// validateToken проверяет подпись и срок действия токена, а также соответствие хэша текущему паролю
+// Возвращает id пользователя, которому выдан токен
+func (s *Server) validateToken(tokenString string) (int64, error) {
+	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (any, error) {
+		return s.secretKey()
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return 0, fmt.Errorf("invalid token: %w", err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, errors.New("invalid token claims")
+	}
+	uidClaim, _ := claims["uid"].(float64)
+	uid := int64(uidClaim)
+	hash, _ := claims["hash"].(string)
+
+	secret := getPassword()
+	if uid != 0 {
+		user, err := s.store.GetUser(uid)
+		if err != nil {
+			return 0, err
+		}
+		secret = user.PasswordHash
+	}
+	if secret != "" && hash != tokenHash(secret) {
+		return 0, errors.New("password has been changed")
+	}
+	return uid, nil
+}
+
+// isOwner проверяет, что запрос выполнен с токеном общего списка задач, выданным по паролю TODO_PASSWORD
+func (s *Server) isOwner(r *http.Request) bool {
+	token := tokenFromRequest(r)
+	if getPassword() == "" || token == "" || strings.HasPrefix(token, apiTokenPrefix) {
+		return false
+	}
+	uid, err := s.validateToken(token)
+	return err == nil && uid == 0
+}
+
+// tokenFromRequest извлекает токен из cookie "token" или заголовка Authorization
+func tokenFromRequest(r *http.Request) string {
+	if cookie, err := r.Cookie("token"); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+	header := strings.TrimSpace(r.Header.Get("Authorization"))
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return header
+}
+
+// userID возвращает id пользователя, сохраненный middleware в контексте запроса
+// 0 соответствует общему списку задач
+func userID(r *http.Request) int64 {
+	uid, _ := r.Context().Value(userKey).(int64)
+	return uid
+}
+
+// viaAPIToken сообщает, аутентифицирован ли запрос персональным API-токеном
+func viaAPIToken(r *http.Request) bool {
+	ok, _ := r.Context().Value(apiTokenKey).(bool)
+	return ok
+}
+
+// auth — middleware для проверки аутентификации перед вызовом обработчика
+// Запрос с токеном выполняется от имени владельца токена, API-токен только для чтения разрешает лишь GET-запросы
+// Запрос без токена работает с общим списком задач, если пароль в TODO_PASSWORD не задан
+func (s *Server) auth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := tokenFromRequest(r)
+		if token == "" {
+			if getPassword() != "" {
+				writeJson(w, http.StatusUnauthorized, db.Response{Error: "Authentication required"})
+				return
+			}
+			next(w, r)
+			return
+		}
+
+		if strings.HasPrefix(token, apiTokenPrefix) {
+			apiToken, err := s.checkAPIToken(token)
+			if err != nil {
+				writeJson(w, http.StatusUnauthorized, db.Response{Error: "Authentication required"})
+				return
+			}
+			if apiToken.ReadOnly && r.Method != http.MethodGet && r.Method != http.MethodHead {
+				writeJson(w, http.StatusForbidden, db.Response{Error: "Token is read-only"})
+				return
+			}
+			ctx := context.WithValue(r.Context(), userKey, apiToken.UserID)
+			next(w, r.WithContext(context.WithValue(ctx, apiTokenKey, true)))
+			return
+		}
+
+		uid, err := s.validateToken(token)
+		if err != nil {
+			writeJson(w, http.StatusUnauthorized, db.Response{Error: "Authentication required"})
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), userKey, uid)))
+	}
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/eOne007/final-project-yapr/pkg/db"
+)
+
+// CompletionDays - период журнала выполнения по умолчанию, MaxCompletionDays - наибольший допустимый период
+const (
+	CompletionDays    = 30
+	MaxCompletionDays = 366
+)
+
+// HistoryResp - структура ответа с историей выполнения задачи
+type HistoryResp struct {
+	Completions []*db.Completion `json:"completions"`
+}
+
+// CompletionsResp - структура ответа с журналом выполнения задач за период
+type CompletionsResp struct {
+	From        string           `json:"from"`
+	To          string           `json:"to"`
+	Completions []*db.Completion `json:"completions"`
+}
+
+// taskHistoryHandler обрабатывает GET-запрос истории выполнения задачи по id
+// История удаленной задачи остается доступной, пока в журнале есть ее отметки
+func (s *Server) taskHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJson(w, http.StatusMethodNotAllowed, db.Response{Error: "Method not allowed"})
+		return
+	}
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: "id is required"})
+		return
+	}
+
+	completions, err := s.store.TaskHistory(userID(r), id)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
+		return
+	}
+	if len(completions) == 0 {
+		if _, err := s.store.Get(userID(r), id); err != nil {
+			if errors.Is(err, db.ErrTaskNotFound) {
+				writeJson(w, http.StatusNotFound, db.Response{Error: err.Error()})
+			} else {
+				writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
+			}
+			return
+		}
+		completions = []*db.Completion{}
+	}
+	writeJson(w, http.StatusOK, HistoryResp{Completions: completions})
+}
+
+// completionsHandler обрабатывает GET-запрос журнала выполнения задач за период from - to включительно
+// Дни периода считаются в часовом поясе пользователя, по умолчанию период - 30 дней по сегодняшний
+func (s *Server) completionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJson(w, http.StatusMethodNotAllowed, db.Response{Error: "Method not allowed"})
+		return
+	}
+
+	loc, err := s.location(userID(r), "")
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
+		return
+	}
+	now, err := s.today(r, "")
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
+		return
+	}
+
+	var bounds [2]time.Time
+	for i, name := range []string{"from", "to"} {
+		if v := r.URL.Query().Get(name); v != "" {
+			date, err := parseDate(v, now)
+			if err != nil {
+				writeJson(w, http.StatusBadRequest, db.Response{Error: fmt.Sprintf("%s: %v", name, err)})
+				return
+			}
+			bounds[i], _ = time.Parse(db.DateFormat, date)
+		}
+	}
+	from, to := bounds[0], bounds[1]
+	if to.IsZero() {
+		to = now
+	}
+	if from.IsZero() {
+		from = to.AddDate(0, 0, 1-CompletionDays)
+	}
+	if to.Before(from) {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: "to must not be earlier than from"})
+		return
+	}
+	if to.After(from.AddDate(0, 0, MaxCompletionDays-1)) {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: fmt.Sprintf("period must not be longer than %d days", MaxCompletionDays)})
+		return
+	}
+
+	// границы периода - полночь первого дня и полночь дня после последнего в часовом поясе пользователя
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	end := time.Date(to.Year(), to.Month(), to.Day()+1, 0, 0, 0, 0, loc)
+	completions, err := s.store.Completions(userID(r), start, end)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
+		return
+	}
+	if completions == nil {
+		completions = []*db.Completion{}
+	}
+	writeJson(w, http.StatusOK, CompletionsResp{
+		From:        from.Format(db.DateFormat),
+		To:          to.Format(db.DateFormat),
+		Completions: completions,
+	})
+}
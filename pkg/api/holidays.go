@@ -0,0 +1,426 @@
+package api
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eOne007/final-project-yapr/internal/repeater"
+	"github.com/eOne007/final-project-yapr/pkg/db"
+)
+
+// MaxHolidaysImport - наибольший размер импортируемого файла календаря в байтах
+// MaxHolidayDays - наибольшая длина одного события календаря в днях
+// MaxHolidayRepeats - наибольшее число повторений события календаря с RRULE
+// MaxImportDays - наибольшее число дней в одном импортируемом календаре
+// MaxHolidayName - наибольшая длина названия дня календаря в байтах
+const (
+	MaxHolidaysImport = 1 << 20
+	MaxHolidayDays    = 366
+	MaxHolidayRepeats = 1000
+	MaxImportDays     = 10000
+	MaxHolidayName    = 255
+)
+
+// HolidaysResp - структура ответа со списком дней календаря
+type HolidaysResp struct {
+	Holidays []*db.Holiday `json:"holidays"`
+}
+
+// ImportResp - структура ответа на импорт календаря
+type ImportResp struct {
+	Imported int `json:"imported"`
+}
+
+// holidaysHandler - маршрутизатор для эндпойнта /holidays
+func (s *Server) holidaysHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.listHolidaysHandler(w, r)
+	case http.MethodPost:
+		s.addHolidayHandler(w, r)
+	case http.MethodDelete:
+		s.deleteHolidayHandler(w, r)
+	default:
+		writeJson(w, http.StatusMethodNotAllowed, db.Response{Error: "Method not allowed"})
+	}
+}
+
+// listHolidaysHandler обрабатывает GET-запрос на получение дней календаря за период from - to
+// Без from и to возвращается весь календарь
+func (s *Server) listHolidaysHandler(w http.ResponseWriter, r *http.Request) {
+	now, err := s.today(r, "")
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
+		return
+	}
+	var bounds [2]string
+	for i, name := range []string{"from", "to"} {
+		if v := r.URL.Query().Get(name); v != "" {
+			date, err := parseDate(v, now)
+			if err != nil {
+				writeJson(w, http.StatusBadRequest, db.Response{Error: fmt.Sprintf("%s: %v", name, err)})
+				return
+			}
+			bounds[i] = date
+		}
+	}
+
+	holidays, err := s.store.Holidays(userID(r), bounds[0], bounds[1])
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, db.Response{Error: err.Error()})
+		return
+	}
+	if holidays == nil {
+		holidays = []*db.Holiday{}
+	}
+	writeJson(w, http.StatusOK, HolidaysResp{Holidays: holidays})
+}
+
+// addHolidayHandler обрабатывает POST-запрос на добавление дня в календарь,
+// день с той же датой заменяется
+func (s *Server) addHolidayHandler(w http.ResponseWriter, r *http.Request) {
+	var holiday db.Holiday
+	if err := json.NewDecoder(r.Body).Decode(&holiday); err != nil {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: "Incorrect JSON format"})
+		return
+	}
+
+	now, err := s.today(r, "")
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
+		return
+	}
+	date, err := parseDate(holiday.Date, now)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: err.Error()})
+		return
+	}
+	holiday.Date = date
+	holiday.Name = strings.TrimSpace(holiday.Name)
+	if len(holiday.Name) > MaxHolidayName {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: "'Name' field is too long"})
+		return
+	}
+
+	if err := s.store.SetHolidays(userID(r), []*db.Holiday{&holiday}); err != nil {
+		writeJson(w, http.StatusInternalServerError, db.Response{Error: err.Error()})
+		return
+	}
+	writeJson(w, http.StatusCreated, holiday)
+}
+
+// deleteHolidayHandler обрабатывает DELETE-запрос на удаление дня из календаря
+func (s *Server) deleteHolidayHandler(w http.ResponseWriter, r *http.Request) {
+	value := r.URL.Query().Get("date")
+	if value == "" {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: "date is required"})
+		return
+	}
+	now, err := s.today(r, "")
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
+		return
+	}
+	date, err := parseDate(value, now)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: err.Error()})
+		return
+	}
+
+	if err := s.store.DeleteHoliday(userID(r), date); err != nil {
+		if errors.Is(err, db.ErrHolidayNotFound) {
+			writeJson(w, http.StatusNotFound, db.Response{Error: err.Error()})
+		} else {
+			writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
+		}
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]string{})
+}
+
+// importHolidaysHandler обрабатывает POST-запрос на импорт календаря из файла .ics или CSV в теле запроса
+// Формат задается параметром format (ics или csv) или заголовком Content-Type
+func (s *Server) importHolidaysHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJson(w, http.StatusMethodNotAllowed, db.Response{Error: "Method not allowed"})
+		return
+	}
+
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "text/calendar":
+			format = "ics"
+		case "text/csv":
+			format = "csv"
+		}
+	}
+
+	body := http.MaxBytesReader(w, r.Body, MaxHolidaysImport)
+	var holidays []*db.Holiday
+	var err error
+	switch format {
+	case "ics":
+		holidays, err = parseICS(body)
+	case "csv":
+		holidays, err = parseHolidaysCSV(body)
+	default:
+		writeJson(w, http.StatusBadRequest, db.Response{Error: "format must be ics or csv"})
+		return
+	}
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: err.Error()})
+		return
+	}
+
+	if err := s.store.SetHolidays(userID(r), holidays); err != nil {
+		writeJson(w, http.StatusInternalServerError, db.Response{Error: err.Error()})
+		return
+	}
+	writeJson(w, http.StatusOK, ImportResp{Imported: len(holidays)})
+}
+
+// calendarHorizon - на сколько дней вперед загружается календарь праздников:
+// дата по правилу b с наибольшим интервалом 400 рабочих дней и по правилу bm наступает раньше
+const calendarHorizon = 2 * 366
+
+// calendar загружает календарь праздников пользователя для расчета правил repeats по рабочим дням
+// Загружаются дни от начала месяца from до более поздней из дат from и to и еще calendarHorizon дней после нее,
+// нулевой to не ограничивает период
+// Если ни одно из правил не считается по рабочим дням, календарь не нужен: возвращается nil без запроса к хранилищу
+func (s *Server) calendar(userID int64, from, to time.Time, repeats ...string) (repeater.Calendar, error) {
+	if !slices.ContainsFunc(repeats, usesCalendar) {
+		return nil, nil
+	}
+	first := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC).Format(db.DateFormat)
+	last := ""
+	if !to.IsZero() {
+		if from.After(to) {
+			to = from
+		}
+		last = to.AddDate(0, 0, calendarHorizon).Format(db.DateFormat)
+	}
+	holidays, err := s.store.Holidays(userID, first, last)
+	if err != nil {
+		return nil, err
+	}
+	cal := make(repeater.Holidays, len(holidays))
+	for _, h := range holidays {
+		cal[h.Date] = h.Working
+	}
+	return cal, nil
+}
+
+// usesCalendar проверяет, что правило повторения b или bm считается по рабочим дням
+func usesCalendar(repeat string) bool {
+	rule, err := repeater.Parse(repeat)
+	if err != nil {
+		return false
+	}
+	switch rule.(type) {
+	case repeater.Business, repeater.BusinessMonthly:
+		return true
+	}
+	return false
+}
+
+// parseICS разбирает календарь iCalendar: каждое событие VEVENT делает нерабочими дни от DTSTART до DTEND,
+// DTEND в дату не входит, как и у событий на целый день
+// Повторяющиеся события с RRULE разворачиваются в отдельные дни, в ошибках указываются номера строк файла
+func parseICS(r io.Reader) ([]*db.Holiday, error) {
+	var holidays []*db.Holiday
+	var event map[string]string
+	// property обрабатывает свойство календаря, line - номер строки файла, с которой оно начинается
+	property := func(text string, line int) error {
+		name, value, ok := strings.Cut(text, ":")
+		if !ok {
+			return nil
+		}
+		name, _, _ = strings.Cut(strings.ToUpper(name), ";")
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			event = make(map[string]string)
+		case name == "END" && strings.EqualFold(value, "VEVENT") && event != nil:
+			days, err := icsEventDays(event, MaxImportDays-len(holidays))
+			if err != nil {
+				return fmt.Errorf("event ending at line %d: %w", line, err)
+			}
+			holidays = append(holidays, days...)
+			event = nil
+		case event != nil:
+			event[name] = value
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxHolidaysImport)
+	var text string
+	start := 0
+	for line := 1; scanner.Scan(); line++ {
+		raw := strings.TrimSuffix(scanner.Text(), "\r")
+		// строки, начинающиеся с пробела или табуляции, продолжают предыдущую строку
+		if start > 0 && (strings.HasPrefix(raw, " ") || strings.HasPrefix(raw, "\t")) {
+			text += raw[1:]
+			continue
+		}
+		if start > 0 {
+			if err := property(text, start); err != nil {
+				return nil, err
+			}
+		}
+		text, start = raw, line
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading calendar: %w", err)
+	}
+	if start > 0 {
+		if err := property(text, start); err != nil {
+			return nil, err
+		}
+	}
+	return holidays, nil
+}
+
+// icsEventDays возвращает нерабочие дни события iCalendar, но не больше limit дней
+// Даты повторений по RRULE считаются пакетом repeater; правило должно ограничивать число повторений
+// COUNT или UNTIL, исключения EXDATE и дополнительные даты RDATE не поддерживаются
+func icsEventDays(event map[string]string, limit int) ([]*db.Holiday, error) {
+	parse := func(value string) (time.Time, error) {
+		if len(value) < 8 {
+			return time.Time{}, fmt.Errorf("incorrect date %q", value)
+		}
+		date, err := time.Parse(db.DateFormat, value[:8])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("incorrect date %q", value)
+		}
+		return date, nil
+	}
+
+	start, err := parse(event["DTSTART"])
+	if err != nil {
+		return nil, fmt.Errorf("DTSTART: %w", err)
+	}
+	end := start.AddDate(0, 0, 1)
+	if value, ok := event["DTEND"]; ok {
+		if end, err = parse(value); err != nil {
+			return nil, fmt.Errorf("DTEND: %w", err)
+		}
+		if !end.After(start) {
+			end = start.AddDate(0, 0, 1)
+		}
+	}
+	if end.After(start.AddDate(0, 0, MaxHolidayDays)) {
+		return nil, fmt.Errorf("event must not be longer than %d days", MaxHolidayDays)
+	}
+	for _, name := range []string{"EXDATE", "RDATE"} {
+		if _, ok := event[name]; ok {
+			return nil, fmt.Errorf("%s is not supported", name)
+		}
+	}
+
+	starts := []time.Time{start}
+	if value, ok := event["RRULE"]; ok {
+		rule, err := repeater.Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("RRULE: %w", err)
+		}
+		if rrule, ok := rule.(repeater.RRule); !ok || rrule.Count == 0 && rrule.Until.IsZero() {
+			return nil, errors.New("RRULE: COUNT or UNTIL is required")
+		}
+		for date, err := range repeater.Dates(start, value, start) {
+			if err != nil {
+				return nil, fmt.Errorf("RRULE: %w", err)
+			}
+			if len(starts) == MaxHolidayRepeats {
+				return nil, fmt.Errorf("RRULE: event must not repeat more than %d times", MaxHolidayRepeats)
+			}
+			starts = append(starts, date)
+		}
+	}
+
+	name := strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\N`, " ", `\\`, `\`).Replace(event["SUMMARY"])
+	name = strings.TrimSpace(name)
+	if len(name) > MaxHolidayName {
+		return nil, fmt.Errorf("SUMMARY must not be longer than %d bytes", MaxHolidayName)
+	}
+	var days []*db.Holiday
+	for _, from := range starts {
+		to := from.Add(end.Sub(start))
+		for d := from; d.Before(to); d = d.AddDate(0, 0, 1) {
+			if len(days) >= limit {
+				return nil, fmt.Errorf("calendar must not contain more than %d days", MaxImportDays)
+			}
+			days = append(days, &db.Holiday{Date: d.Format(db.DateFormat), Name: name})
+		}
+	}
+	return days, nil
+}
+
+// parseHolidaysCSV разбирает календарь CSV со строками дата,название[,рабочий день]
+// Дата записывается как 20060102, 2006-01-02 или 02.01.2006, первая строка может быть заголовком
+func parseHolidaysCSV(r io.Reader) ([]*db.Holiday, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var holidays []*db.Holiday
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading CSV: %w", err)
+		}
+		if len(record) == 0 || strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		date, ok := parseCSVDate(strings.TrimSpace(record[0]))
+		if !ok {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: incorrect date %q", line, record[0])
+		}
+		holiday := &db.Holiday{Date: date}
+		if len(record) > 1 {
+			holiday.Name = strings.TrimSpace(record[1])
+		}
+		if len(holiday.Name) > MaxHolidayName {
+			return nil, fmt.Errorf("line %d: name must not be longer than %d bytes", line, MaxHolidayName)
+		}
+		if len(record) > 2 && strings.TrimSpace(record[2]) != "" {
+			if holiday.Working, err = strconv.ParseBool(strings.TrimSpace(record[2])); err != nil {
+				return nil, fmt.Errorf("line %d: working day flag must be true or false, got %q", line, record[2])
+			}
+		}
+		if len(holidays) == MaxImportDays {
+			return nil, fmt.Errorf("line %d: calendar must not contain more than %d days", line, MaxImportDays)
+		}
+		holidays = append(holidays, holiday)
+	}
+	return holidays, nil
+}
+
+// parseCSVDate разбирает дату строки календаря CSV
+func parseCSVDate(value string) (string, bool) {
+	for _, layout := range []string{db.DateFormat, "2006-01-02", "02.01.2006"} {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date.Format(db.DateFormat), true
+		}
+	}
+	return "", false
+}
@@ -3,6 +3,8 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/eOne007/final-project-yapr/internal/repeater"
@@ -10,7 +12,7 @@ import (
 )
 
 // nextDayHandler обрабатывает GET-запрос для вычисления следующей даты выполнения задачи
-	func nextDayHandler(w http.ResponseWriter, r *http.Request) {
+	func (s *Server) nextDayHandler(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			writeJson(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
 			return
@@ -30,17 +32,29 @@ import (
 			return
 		}
 
-		now := time.Now().UTC()
-		
+		now, err := s.today(r, "")
+		if err != nil {
+			writeJson(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
+			return
+		}
 		if getNow != "" {
-			var err error
 			now, err = time.Parse(db.DateFormat, getNow)
 			if err != nil {
 				writeJson(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Invalid 'now' parameter: %v", err)})
 				return
 			}
 		}
-		nextDate, err := repeater.NextDate(now, getDate, getRepeat)
+		// неверную дату NextDateIn отклонит сама, календарь для нее не нужен
+		start, err := time.Parse(db.DateFormat, getDate)
+		if err != nil {
+			start = now
+		}
+		cal, err := s.calendar(userID(r), start, now, getRepeat)
+		if err != nil {
+			writeJson(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
+			return
+		}
+		nextDate, err := repeater.NextDateIn(cal, now, getDate, getRepeat)
 			if err != nil {
 				writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 				return
@@ -49,3 +63,147 @@ import (
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprint(w, nextDate) 
 	}
+
+// NextDatesLimit - количество дат по умолчанию, MaxNextDatesLimit - наибольшее количество дат в ответе
+const (
+	NextDatesLimit    = 5
+	MaxNextDatesLimit = 100
+)
+
+// NextDatesResp - структура ответа со списком ближайших дат повторения
+type NextDatesResp struct {
+	Dates []string `json:"dates"`
+}
+
+// nextDatesHandler обрабатывает GET-запрос для вычисления n ближайших дат повторения задачи после now
+// Без date началом повторений считается now, без now - сегодняшний день
+func (s *Server) nextDatesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	getRepeat := r.FormValue("repeat")
+	if getRepeat == "" {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Empty parameter: repeat"})
+		return
+	}
+
+	now, err := s.today(r, "")
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		return
+	}
+	if getNow := r.FormValue("now"); getNow != "" {
+		now, err = time.Parse(db.DateFormat, getNow)
+		if err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Invalid 'now' parameter: %v", err)})
+			return
+		}
+	}
+
+	start := now
+	if getDate := r.FormValue("date"); getDate != "" {
+		var err error
+		start, err = time.Parse(db.DateFormat, getDate)
+		if err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Invalid 'date' parameter: %v", err)})
+			return
+		}
+	}
+
+	n := NextDatesLimit
+	if getN := r.FormValue("n"); getN != "" {
+		var err error
+		n, err = strconv.Atoi(getN)
+		if err != nil || n < 1 || n > MaxNextDatesLimit {
+			writeJson(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Parameter 'n' must be a number from 1 to %d", MaxNextDatesLimit)})
+			return
+		}
+	}
+
+	// сколько дней займут n дат, заранее неизвестно, поэтому календарь загружается без верхней границы
+	cal, err := s.calendar(userID(r), start, time.Time{}, getRepeat)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		return
+	}
+	dates, err := repeater.OccurrencesIn(cal, start, getRepeat, now, n)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	resp := NextDatesResp{Dates: make([]string, 0, len(dates))}
+	for _, date := range dates {
+		resp.Dates = append(resp.Dates, date.Format(db.DateFormat))
+	}
+	writeJson(w, http.StatusOK, resp)
+}
+
+// RRuleResp - структура ответа с правилом повторения в записи RRULE
+type RRuleResp struct {
+	RRule string `json:"rrule"`
+}
+
+// rruleHandler обрабатывает GET-запрос перевода правила повторения в запись RRULE из RFC 5545
+func rruleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	getRepeat := r.FormValue("repeat")
+	if getRepeat == "" {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Empty parameter: repeat"})
+		return
+	}
+
+	rule, err := repeater.Parse(getRepeat)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	rrule, err := repeater.ToRRule(rule)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJson(w, http.StatusOK, RRuleResp{RRule: rrule})
+}
+
+// describeRepeat заполняет у задач описание правила повторения на языке из заголовка Accept-Language
+func describeRepeat(r *http.Request, tasks ...*db.Task) {
+	lang := language(r)
+	for _, task := range tasks {
+		if task.Repeat == "" {
+			continue
+		}
+		if rule, err := repeater.Parse(task.Repeat); err == nil {
+			task.RepeatText = repeater.Describe(rule, lang)
+		}
+	}
+}
+
+// language выбирает язык описаний по заголовку Accept-Language: поддерживаемый язык с наибольшим весом q,
+// без заголовка или без поддерживаемых языков - русский
+func language(r *http.Request) string {
+	lang, best := repeater.LangRussian, 0.0
+	for _, item := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		primary, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if primary != repeater.LangRussian && primary != repeater.LangEnglish {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > best {
+			lang, best = primary, q
+		}
+	}
+	return lang
+}
@@ -0,0 +1,368 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/eOne007/final-project-yapr/pkg/db"
+)
+
+// QueryError - ошибка разбора поискового запроса с позицией ошибочной лексемы
+// Позиция считается в символах с единицы
+type QueryError struct {
+	Pos   int
+	Token string
+	Msg   string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("invalid search query at position %d (%q): %s", e.Pos, e.Token, e.Msg)
+}
+
+// виды лексем поискового запроса
+const (
+	tokWord   = iota // слово, фраза в кавычках или условие поле:значение
+	tokLParen        // (
+	tokRParen        // )
+	tokNeg           // - перед условием
+	tokAnd           // AND
+	tokOr            // OR
+	tokNot           // NOT
+)
+
+// queryToken - лексема поискового запроса
+type queryToken struct {
+	kind int
+	text string
+	pos  int
+}
+
+// tokenizeQuery разбивает поисковый запрос на лексемы
+// Ключевые слова AND, OR и NOT распознаются только в верхнем регистре,
+// минус означает отрицание, только если за ним без пробела следует условие
+func tokenizeQuery(query string) ([]queryToken, error) {
+	runes := []rune(query)
+	var tokens []queryToken
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '(':
+			tokens = append(tokens, queryToken{kind: tokLParen, text: "(", pos: i + 1})
+			i++
+			continue
+		case r == ')':
+			tokens = append(tokens, queryToken{kind: tokRParen, text: ")", pos: i + 1})
+			i++
+			continue
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && runes[i+1] != ')':
+			tokens = append(tokens, queryToken{kind: tokNeg, text: "-", pos: i + 1})
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' {
+			if runes[i] == '"' {
+				end := i + 1
+				for end < len(runes) && runes[end] != '"' {
+					end++
+				}
+				if end == len(runes) {
+					return nil, &QueryError{Pos: i + 1, Token: string(runes[i:]), Msg: "unterminated quote"}
+				}
+				i = end
+			}
+			i++
+		}
+		token := queryToken{kind: tokWord, text: string(runes[start:i]), pos: start + 1}
+		switch token.text {
+		case "AND":
+			token.kind = tokAnd
+		case "OR":
+			token.kind = tokOr
+		case "NOT":
+			token.kind = tokNot
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// queryParser - разбор поискового запроса методом рекурсивного спуска
+// Приоритет операций: отрицание, затем AND (в том числе неявное между условиями), затем OR
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+	now    time.Time
+}
+
+// parseQuery разбирает поисковый запрос в условие структурированного поиска
+// Возвращает nil, если запрос не содержит ни одного условия
+func parseQuery(query string, now time.Time) (*db.Filter, error) {
+	tokens, err := tokenizeQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens, now: now}
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		token := p.tokens[p.pos]
+		return nil, &QueryError{Pos: token.pos, Token: token.text, Msg: "unexpected closing parenthesis"}
+	}
+	return filter, nil
+}
+
+// peek возвращает текущую лексему, ok равно false в конце запроса
+func (p *queryParser) peek() (queryToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return queryToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// parseOr разбирает условия, объединенные через OR
+func (p *queryParser) parseOr() (*db.Filter, error) {
+	var children []*db.Filter
+	for {
+		child, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if child != nil {
+			children = append(children, child)
+		}
+		token, ok := p.peek()
+		if !ok || token.kind != tokOr {
+			break
+		}
+		if err := p.binary(token); err != nil {
+			return nil, err
+		}
+	}
+	return group(db.FilterOr, children), nil
+}
+
+// parseAnd разбирает условия, объединенные через AND или записанные подряд
+func (p *queryParser) parseAnd() (*db.Filter, error) {
+	var children []*db.Filter
+	for {
+		token, ok := p.peek()
+		if !ok || token.kind == tokOr || token.kind == tokRParen {
+			break
+		}
+		if token.kind == tokAnd {
+			if err := p.binary(token); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if child != nil {
+			children = append(children, child)
+		}
+	}
+	return group(db.FilterAnd, children), nil
+}
+
+// binary пропускает оператор AND или OR, проверяя, что он стоит между двумя условиями
+func (p *queryParser) binary(token queryToken) error {
+	valid := p.pos > 0 && (p.tokens[p.pos-1].kind == tokWord || p.tokens[p.pos-1].kind == tokRParen)
+	p.pos++
+	if next, ok := p.peek(); !ok || next.kind == tokOr || next.kind == tokAnd || next.kind == tokRParen {
+		valid = false
+	}
+	if !valid {
+		return &QueryError{Pos: token.pos, Token: token.text, Msg: token.text + " must be placed between two conditions"}
+	}
+	return nil
+}
+
+// parseUnary разбирает условие с необязательным отрицанием
+func (p *queryParser) parseUnary() (*db.Filter, error) {
+	token, _ := p.peek()
+	if token.kind != tokNeg && token.kind != tokNot {
+		return p.parsePrimary()
+	}
+	p.pos++
+	if next, ok := p.peek(); !ok || next.kind == tokOr || next.kind == tokAnd || next.kind == tokRParen {
+		return nil, &QueryError{Pos: token.pos, Token: token.text, Msg: "negation must be followed by a condition"}
+	}
+	child, err := p.parseUnary()
+	if err != nil || child == nil {
+		return nil, err
+	}
+	return &db.Filter{Op: db.FilterNot, Children: []*db.Filter{child}}, nil
+}
+
+// parsePrimary разбирает условие в скобках или отдельное условие
+func (p *queryParser) parsePrimary() (*db.Filter, error) {
+	token, _ := p.peek()
+	p.pos++
+	if token.kind != tokLParen {
+		return p.parseTerm(token)
+	}
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if next, ok := p.peek(); !ok || next.kind != tokRParen {
+		return nil, &QueryError{Pos: token.pos, Token: token.text, Msg: "unclosed parenthesis"}
+	}
+	p.pos++
+	return filter, nil
+}
+
+// parseTerm разбирает слово, фразу в кавычках или условие поле:значение
+// Слова без букв и цифр пропускаются, как и при полнотекстовом поиске
+func (p *queryParser) parseTerm(token queryToken) (*db.Filter, error) {
+	field, value, ok := strings.Cut(token.text, ":")
+	if !ok || !isFieldName(field) {
+		if date, err := time.Parse("02.01.2006", token.text); err == nil {
+			return &db.Filter{Field: db.FieldOn, Value: date.Format(db.DateFormat)}, nil
+		}
+		return textTerm(db.FieldText, token.text), nil
+	}
+
+	fail := func(msg string) (*db.Filter, error) {
+		return nil, &QueryError{Pos: token.pos, Token: token.text, Msg: msg}
+	}
+	if value == "" {
+		return fail(fmt.Sprintf("missing value for %q", field))
+	}
+
+	switch strings.ToLower(field) {
+	case db.FieldTitle, db.FieldComment:
+		term := textTerm(strings.ToLower(field), value)
+		if term == nil {
+			return fail("value must contain letters or digits")
+		}
+		return term, nil
+	case db.FieldRepeat:
+		term, err := repeatTerm(value)
+		if err != nil {
+			return fail(err.Error())
+		}
+		return term, nil
+	case db.FieldBefore, db.FieldAfter, db.FieldOn:
+		date, err := parseDate(value, p.now)
+		if err != nil {
+			return fail(err.Error())
+		}
+		return &db.Filter{Field: strings.ToLower(field), Value: date}, nil
+	case "is":
+		switch strings.ToLower(value) {
+		case "recurring":
+			return &db.Filter{Field: db.FieldRepeat, Value: db.RepeatAny}, nil
+		case "once":
+			return &db.Filter{Field: db.FieldRepeat, Value: db.RepeatNone}, nil
+		case "overdue":
+			return &db.Filter{Field: db.FieldBefore, Value: p.now.Format(db.DateFormat)}, nil
+		}
+		return fail("is must be one of recurring, once, overdue")
+	}
+	return fail(fmt.Sprintf("unknown field %q", field))
+}
+
+// parseDate разбирает дату условия: 02.01.2006, 20060102, today, tomorrow или yesterday
+func parseDate(value string, now time.Time) (string, error) {
+	switch strings.ToLower(value) {
+	case "today":
+		return now.Format(db.DateFormat), nil
+	case "tomorrow":
+		return now.AddDate(0, 0, 1).Format(db.DateFormat), nil
+	case "yesterday":
+		return now.AddDate(0, 0, -1).Format(db.DateFormat), nil
+	}
+	for _, layout := range []string{"02.01.2006", db.DateFormat} {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date.Format(db.DateFormat), nil
+		}
+	}
+	return "", fmt.Errorf("date must be DD.MM.YYYY, YYYYMMDD, today, tomorrow or yesterday")
+}
+
+// repeatTerm создает условие на тип правила повторения: none, any, d, w, m, y, b или bm
+func repeatTerm(value string) (*db.Filter, error) {
+	switch value = strings.ToLower(value); value {
+	case db.RepeatNone, db.RepeatAny, "d", "w", "m", "y", "b", "bm":
+		return &db.Filter{Field: db.FieldRepeat, Value: value}, nil
+	}
+	return nil, fmt.Errorf("repeat must be one of none, any, d, w, m, y, b, bm")
+}
+
+// isFieldName проверяет, что текст перед двоеточием похож на имя поля, а не на часть слова вроде 18:00
+func isFieldName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r > unicode.MaxASCII || !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// textTerm создает текстовое условие, значение в кавычках ищется как точная фраза
+// Возвращает nil, если в значении нет букв и цифр
+func textTerm(field, value string) *db.Filter {
+	phrase := len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`)
+	value = strings.ReplaceAll(value, `"`, " ")
+	if strings.IndexFunc(value, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) < 0 {
+		return nil
+	}
+	return &db.Filter{Field: field, Value: strings.TrimSpace(value), Phrase: phrase}
+}
+
+// group объединяет условия, одиночное условие возвращается без группы
+func group(op db.FilterOp, children []*db.Filter) *db.Filter {
+	switch len(children) {
+	case 0:
+		return nil
+	case 1:
+		return children[0]
+	}
+	return &db.Filter{Op: op, Children: children}
+}
+
+// plainSearch восстанавливает строку полнотекстового поиска из условия, состоящего только из слов и фраз
+func plainSearch(filter *db.Filter) string {
+	terms := filter.Children
+	if filter.Op == db.FilterTerm {
+		terms = []*db.Filter{filter}
+	}
+	parts := make([]string, 0, len(terms))
+	for _, term := range terms {
+		if term.Phrase {
+			parts = append(parts, `"`+term.Value+`"`)
+		} else {
+			parts = append(parts, term.Value)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// isPlainText проверяет, что условие состоит только из слов и фраз без полей и операторов,
+// такой запрос выполняется полнотекстовым поиском с ранжированием и сниппетами
+func isPlainText(filter *db.Filter) bool {
+	if filter.Op == db.FilterAnd {
+		for _, child := range filter.Children {
+			if child.Op != db.FilterTerm || child.Field != db.FieldText {
+				return false
+			}
+		}
+		return true
+	}
+	return filter.Op == db.FilterTerm && filter.Field == db.FieldText
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/eOne007/final-project-yapr/internal/repeater"
+	"github.com/eOne007/final-project-yapr/pkg/db"
+)
+
+// SettingsResp - структура ответа с настройками пользователя
+// Timezone - часовой пояс пользователя, пустая строка - используется часовой пояс сервера ServerTimezone
+type SettingsResp struct {
+	Timezone       string `json:"timezone"`
+	ServerTimezone string `json:"server_timezone"`
+}
+
+// settingsHandler - маршрутизатор для эндпойнта /settings
+func (s *Server) settingsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		tz, err := s.store.Timezone(userID(r))
+		if err != nil {
+			writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
+			return
+		}
+		writeJson(w, http.StatusOK, SettingsResp{Timezone: tz, ServerTimezone: s.loc.String()})
+	case http.MethodPut:
+		s.updateSettingsHandler(w, r)
+	default:
+		writeJson(w, http.StatusMethodNotAllowed, db.Response{Error: "Method not allowed"})
+	}
+}
+
+// updateSettingsHandler обрабатывает PUT-запрос на изменение часового пояса пользователя,
+// пустой часовой пояс возвращает часовой пояс сервера
+func (s *Server) updateSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	var settings SettingsResp
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: "Incorrect JSON format"})
+		return
+	}
+	if settings.Timezone != "" {
+		if _, err := loadLocation(settings.Timezone); err != nil {
+			writeJson(w, http.StatusBadRequest, db.Response{Error: err.Error()})
+			return
+		}
+	}
+
+	if err := s.store.SetTimezone(userID(r), settings.Timezone); err != nil {
+		writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
+		return
+	}
+	writeJson(w, http.StatusOK, SettingsResp{Timezone: settings.Timezone, ServerTimezone: s.loc.String()})
+}
+
+// serverLocation возвращает часовой пояс сервера из переменной окружения TODO_TZ,
+// если она не задана или неверна - местный часовой пояс системы
+func serverLocation() *time.Location {
+	tz := os.Getenv("TODO_TZ")
+	if tz == "" {
+		return time.Local
+	}
+	loc, err := loadLocation(tz)
+	if err != nil {
+		log.Printf("TODO_TZ: %v, используется местный часовой пояс", err)
+		return time.Local
+	}
+	return loc
+}
+
+// loadLocation загружает часовой пояс по имени IANA, например Europe/Moscow
+func loadLocation(tz string) (*time.Location, error) {
+	if tz == "" || tz == "Local" {
+		return nil, fmt.Errorf("unknown timezone %q", tz)
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", tz)
+	}
+	return loc, nil
+}
+
+// location возвращает часовой пояс задачи: tz, если он задан, иначе часовой пояс пользователя или сервера
+func (s *Server) location(userID int64, tz string) (*time.Location, error) {
+	if tz == "" {
+		var err error
+		if tz, err = s.store.Timezone(userID); err != nil {
+			return nil, err
+		}
+	}
+	if tz == "" {
+		return s.loc, nil
+	}
+	return loadLocation(tz)
+}
+
+// today возвращает текущую дату в часовом поясе tz или, если он не задан, пользователя
+// Все проверки и расчеты дат задач выполняются от этой даты
+func (s *Server) today(r *http.Request, tz string) (time.Time, error) {
+	loc, err := s.location(userID(r), tz)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return repeater.Today(time.Now(), loc), nil
+}
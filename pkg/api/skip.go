@@ -0,0 +1,96 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/eOne007/final-project-yapr/pkg/db"
+)
+
+// taskSkipHandler обрабатывает POST-запрос на пропуск текущего повторения задачи id:
+// задача переносится на следующую дату, как при выполнении, но в журнал записывается пропуск
+func (s *Server) taskSkipHandler(w http.ResponseWriter, r *http.Request) {
+	s.advanceTask(w, r, db.CompletionSkipped)
+}
+
+// taskSnoozeHandler обрабатывает POST-запрос на перенос текущего повторения задачи id на дату until
+// Переносится только это повторение: следующие даты серии по-прежнему считаются от его исходной даты
+// Перенос записывается в журнал, в ответе возвращается токен для его отмены
+func (s *Server) taskSnoozeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJson(w, http.StatusMethodNotAllowed, db.Response{Error: "Method not allowed"})
+		return
+	}
+	id, value := r.URL.Query().Get("id"), r.URL.Query().Get("until")
+	if id == "" {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: "id is required"})
+		return
+	}
+	if value == "" {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: "until is required"})
+		return
+	}
+
+	task, err := s.store.Get(userID(r), id)
+	if err != nil {
+		if errors.Is(err, db.ErrTaskNotFound) {
+			writeJson(w, http.StatusNotFound, db.Response{Error: err.Error()})
+		} else {
+			writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
+		}
+		return
+	}
+	now, err := s.today(r, task.Timezone)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
+		return
+	}
+	until, err := parseDate(value, now)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: fmt.Sprintf("until: %v", err)})
+		return
+	}
+	if until < now.Format(db.DateFormat) {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: "until must not be in the past"})
+		return
+	}
+	if until == task.Date {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: "task is already scheduled on this date"})
+		return
+	}
+
+	moved := *task
+	moved.SnoozedFrom, moved.Date = seriesDate(task), until
+	if moved.Repeat == "" || moved.SnoozedFrom == until {
+		moved.SnoozedFrom = ""
+	}
+	completion := &db.Completion{TaskID: task.ID, Kind: db.CompletionSnoozed, Title: task.Title, Date: task.Date,
+		DoneAt: time.Now().UTC().Format(db.TimestampFormat), NextDate: until}
+	s.complete(w, r, completion, &moved)
+}
+
+// seriesDate возвращает дату текущего повторения задачи в серии: исходную дату перенесенного повторения
+// или дату задачи, если повторение не переносилось; от нее считаются следующие даты
+func seriesDate(task *db.Task) string {
+	if task.SnoozedFrom != "" {
+		return task.SnoozedFrom
+	}
+	return task.Date
+}
+
+// checkSnooze проверяет исходную дату перенесенного повторения при обновлении задачи:
+// у задачи без правила повторения и у повторения, возвращенного на исходную дату, она сбрасывается
+func checkSnooze(task *db.Task) error {
+	if task.SnoozedFrom == "" {
+		return nil
+	}
+	if _, err := time.Parse(db.DateFormat, task.SnoozedFrom); err != nil {
+		return fmt.Errorf("incorrect snoozed_from format: %w", err)
+	}
+	if task.Repeat == "" || task.SnoozedFrom == task.Date {
+		task.SnoozedFrom = ""
+	}
+	return nil
+}
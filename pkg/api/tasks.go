@@ -1,16 +1,29 @@
 package api
 
 import (
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/eOne007/final-project-yapr/pkg/db"
 )
 
-const TasksLimit = 30
+// TasksLimit - размер страницы списка задач по умолчанию, MaxTasksLimit - наибольший допустимый размер
+const (
+	TasksLimit    = 30
+	MaxTasksLimit = 500
+)
 
 // TasksResp — структура ответа для списка задач, используем для сериализации в JSON
+// NextCursor передается в параметре cursor для получения следующей страницы, на последней странице он пуст
 type TasksResp struct {
-	Tasks	[]*db.Task	`json:"tasks"`
+	Tasks      []*db.Task `json:"tasks"`
+	NextCursor string     `json:"next_cursor"`
+	Total      int        `json:"total"`
 }
 
 // ErrorResp — структура ответа для ошибок, используем для отправки сообщений об ошибках в формате JSON
@@ -19,26 +32,150 @@ type ErrorResp struct {
 }
 
 // tasksHandler обрабатывает GET-запрос для получения списка задач
-// Реализован с воможностью поиска по заголовку
-func tasksHandler(w http.ResponseWriter, r *http.Request) {
-	limit := TasksLimit // устанавливаем лимит на количество возвращаемых задач
-	search := r.URL.Query().Get("search")
-
-	var tasks []*db.Task
-	var err error
+// Реализован с возможностью поиска: по словам и фразам или по структурированному запросу
+// с условиями на поля, например title:отчёт repeat:w before:01.11.2026 -comment:черновик
+// Список выдается страницами размером limit, следующая страница запрашивается по курсору cursor,
+// параметры from, to, overdue и repeat дополнительно ограничивают как список, так и результаты поиска
+func (s *Server) tasksHandler(w http.ResponseWriter, r *http.Request) {
+	now, err := s.today(r, "")
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, ErrorResp{Error: err.Error()})
+		return
+	}
+	page, err := parsePage(r)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, ErrorResp{Error: err.Error()})
+		return
+	}
+	extra, err := parseListFilter(r, now)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, ErrorResp{Error: err.Error()})
+		return
+	}
 
+	var filter *db.Filter
+	search := r.URL.Query().Get("search")
 	if search != "" {
-		tasks, err = db.TasksWithSearch(limit, search)
-	} else {
-		tasks, err = db.Tasks(limit)
+		filter, err = parseQuery(search, now)
+		if err != nil {
+			writeJson(w, http.StatusBadRequest, ErrorResp{Error: err.Error()})
+			return
+		}
+	}
+
+	var result *db.TaskPage
+	switch {
+	case search == "" && extra == nil:
+		result, err = s.store.List(userID(r), page)
+	case search == "":
+		result, err = s.store.Find(userID(r), page, extra)
+	case filter == nil:
+		result = &db.TaskPage{}
+	case isPlainText(filter):
+		// запрос только из слов и фраз выполняется полнотекстовым поиском со сниппетами
+		result, err = s.store.Search(userID(r), page, plainSearch(filter), extra)
+	default:
+		result, err = s.store.Find(userID(r), page, db.And(filter, extra))
 	}
 	if err != nil {
 		writeJson(w, http.StatusInternalServerError, ErrorResp{Error: err.Error()})
 		return
 	}
 
-	if tasks == nil {
-		tasks = []*db.Task{}
+	resp := TasksResp{Tasks: result.Tasks, Total: result.Total}
+	if resp.Tasks == nil {
+		resp.Tasks = []*db.Task{}
+	}
+	if result.Next != nil {
+		resp.NextCursor = encodeCursor(result.Next)
+	}
+	describeRepeat(r, resp.Tasks...)
+	writeJson(w, http.StatusOK, resp)
+}
+
+// parsePage получает параметры страницы из запроса: limit от 1 до MaxTasksLimit и cursor
+func parsePage(r *http.Request) (db.Page, error) {
+	page := db.Page{Limit: TasksLimit}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > MaxTasksLimit {
+			return page, fmt.Errorf("limit must be a number from 1 to %d", MaxTasksLimit)
+		}
+		page.Limit = limit
+	}
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		cursor, err := decodeCursor(v)
+		if err != nil {
+			return page, err
+		}
+		page.After = cursor
+	}
+	return page, nil
+}
+
+// parseListFilter получает из запроса условия на список задач:
+// from и to - границы дат включительно, overdue=true - задачи с датой раньше сегодняшней,
+// repeat - тип правила повторения none, any, d, w, m или y
+func parseListFilter(r *http.Request, now time.Time) (*db.Filter, error) {
+	var filters []*db.Filter
+	for _, param := range []struct{ name, field string }{{"from", db.FieldFrom}, {"to", db.FieldTo}} {
+		v := r.URL.Query().Get(param.name)
+		if v == "" {
+			continue
+		}
+		date, err := parseDate(v, now)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", param.name, err)
+		}
+		filters = append(filters, &db.Filter{Field: param.field, Value: date})
+	}
+
+	if v := r.URL.Query().Get("overdue"); v != "" {
+		overdue, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("overdue must be true or false")
+		}
+		if overdue {
+			filters = append(filters, &db.Filter{Field: db.FieldBefore, Value: now.Format(db.DateFormat)})
+		}
+	}
+
+	if v := r.URL.Query().Get("repeat"); v != "" {
+		term, err := repeatTerm(v)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, term)
+	}
+	return db.And(filters...), nil
+}
+
+// encodeCursor кодирует позицию в списке в непрозрачную для клиента строку
+func encodeCursor(c *db.Cursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%s:%d", c.Rank, c.Date, c.ID)))
+}
+
+// decodeCursor восстанавливает позицию в списке из строки, полученной от encodeCursor
+func decodeCursor(v string) (*db.Cursor, error) {
+	errCursor := errors.New("invalid cursor")
+	data, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil {
+		return nil, errCursor
+	}
+	parts := strings.Split(string(data), ":")
+	if len(parts) != 3 {
+		return nil, errCursor
+	}
+	rank, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, errCursor
+	}
+	if _, err := time.Parse(db.DateFormat, parts[1]); err != nil {
+		return nil, errCursor
+	}
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, errCursor
 	}
-	writeJson(w, http.StatusOK, TasksResp{Tasks: tasks})
+	return &db.Cursor{Rank: rank, Date: parts[1], ID: id}, nil
 }
\ No newline at end of file
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/eOne007/final-project-yapr/pkg/db"
+)
+
+// TrashRetention - срок хранения задач в корзине по умолчанию
+// TrashPurgeInterval - наибольший интервал между очистками корзины
+const (
+	TrashRetention     = 30 * 24 * time.Hour
+	TrashPurgeInterval = time.Hour
+)
+
+// TrashResp - структура ответа со списком задач в корзине
+type TrashResp struct {
+	Tasks []*db.Task `json:"tasks"`
+}
+
+// EmptyTrashResp - структура ответа на очистку корзины
+type EmptyTrashResp struct {
+	Deleted int64 `json:"deleted"`
+}
+
+// trashRetention возвращает срок хранения задач в корзине из переменной окружения TODO_TRASH_RETENTION
+// в формате time.ParseDuration, например 72h; по умолчанию - TrashRetention
+func trashRetention() time.Duration {
+	value := os.Getenv("TODO_TRASH_RETENTION")
+	if value == "" {
+		return TrashRetention
+	}
+	retention, err := time.ParseDuration(value)
+	if err != nil || retention <= 0 {
+		log.Printf("TODO_TRASH_RETENTION: некорректный срок %q, используется %v", value, TrashRetention)
+		return TrashRetention
+	}
+	return retention
+}
+
+// StartTrashPurge запускает в фоне очистку корзины хранилища store со сроком хранения из TODO_TRASH_RETENTION,
+// очистка прекращается при отмене ctx
+func StartTrashPurge(ctx context.Context, store db.TrashStore) {
+	retention := trashRetention()
+	go PurgeTrash(ctx, store, retention, min(retention, TrashPurgeInterval))
+}
+
+// PurgeTrash каждые interval окончательно удаляет задачи, пробывшие в корзине дольше retention,
+// первая очистка выполняется сразу; функция завершается при отмене ctx
+func PurgeTrash(ctx context.Context, store db.TrashStore, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		count, err := store.PurgeTrash(time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("Ошибка очистки корзины: %v", err)
+		} else if count > 0 {
+			log.Printf("Из корзины удалено задач: %d", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// trashHandler - маршрутизатор для эндпойнта /trash
+func (s *Server) trashHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.listTrashHandler(w, r)
+	case http.MethodDelete:
+		s.emptyTrashHandler(w, r)
+	default:
+		writeJson(w, http.StatusMethodNotAllowed, db.Response{Error: "Method not allowed"})
+	}
+}
+
+// listTrashHandler обрабатывает GET-запрос на получение задач из корзины, сначала удаленные последними
+func (s *Server) listTrashHandler(w http.ResponseWriter, r *http.Request) {
+	tasks, err := s.store.Trash(userID(r))
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
+		return
+	}
+	if tasks == nil {
+		tasks = []*db.Task{}
+	}
+	writeJson(w, http.StatusOK, TrashResp{Tasks: tasks})
+}
+
+// emptyTrashHandler обрабатывает DELETE-запрос на окончательное удаление всех задач из корзины
+func (s *Server) emptyTrashHandler(w http.ResponseWriter, r *http.Request) {
+	count, err := s.store.EmptyTrash(userID(r))
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
+		return
+	}
+	writeJson(w, http.StatusOK, EmptyTrashResp{Deleted: count})
+}
+
+// restoreTaskHandler обрабатывает POST-запрос на возврат задачи id из корзины,
+// в ответе возвращается восстановленная задача
+func (s *Server) restoreTaskHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJson(w, http.StatusMethodNotAllowed, db.Response{Error: "Method not allowed"})
+		return
+	}
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: "id is required"})
+		return
+	}
+
+	if err := s.store.RestoreTask(userID(r), id); err != nil {
+		if errors.Is(err, db.ErrTaskNotFound) {
+			writeJson(w, http.StatusNotFound, db.Response{Error: err.Error()})
+		} else {
+			writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
+		}
+		return
+	}
+	task, err := s.store.Get(userID(r), id)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
+		return
+	}
+	writeJson(w, http.StatusOK, task)
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/eOne007/final-project-yapr/pkg/db"
+)
+
+// UndoWindow - срок действия токена отмены по умолчанию
+const UndoWindow = 5 * time.Minute
+
+// UndoResp - структура ответа на удаление и выполнение задачи с токеном для их отмены
+type UndoResp struct {
+	UndoToken     string `json:"undo_token"`
+	UndoExpiresAt string `json:"undo_expires_at"`
+}
+
+// undoWindow возвращает срок действия токенов отмены из переменной окружения TODO_UNDO_WINDOW
+// в формате time.ParseDuration, например 30s или 10m; по умолчанию - UndoWindow
+func undoWindow() time.Duration {
+	value := os.Getenv("TODO_UNDO_WINDOW")
+	if value == "" {
+		return UndoWindow
+	}
+	window, err := time.ParseDuration(value)
+	if err != nil || window <= 0 {
+		log.Printf("TODO_UNDO_WINDOW: некорректный срок %q, используется %v", value, UndoWindow)
+		return UndoWindow
+	}
+	return window
+}
+
+// newUndo создает отметку отмены со случайным токеном, действующую s.undoWindow
+func (s *Server) newUndo() (*db.Undo, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	return &db.Undo{
+		Token:     hex.EncodeToString(buf),
+		ExpiresAt: time.Now().Add(s.undoWindow).UTC().Format(db.TimestampFormat),
+	}, nil
+}
+
+// undoHandler обрабатывает POST-запрос на отмену удаления или выполнения задачи по токену token
+// Задача возвращается в состояние до операции с прежним id, отметка о выполнении удаляется из журнала
+func (s *Server) undoHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJson(w, http.StatusMethodNotAllowed, db.Response{Error: "Method not allowed"})
+		return
+	}
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		writeJson(w, http.StatusBadRequest, db.Response{Error: "token is required"})
+		return
+	}
+
+	task, err := s.store.Undo(userID(r), token, time.Now())
+	if err != nil {
+		if errors.Is(err, db.ErrUndoNotFound) {
+			writeJson(w, http.StatusNotFound, db.Response{Error: err.Error()})
+		} else {
+			writeJson(w, http.StatusInternalServerError, db.Response{Error: "Database error"})
+		}
+		return
+	}
+	writeJson(w, http.StatusOK, task)
+}
@@ -0,0 +1,120 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimestampFormat - формат отметок времени в БД: RFC 3339 в UTC без долей секунды,
+// поэтому отметки можно сравнивать как строки
+const TimestampFormat = "2006-01-02T15:04:05Z"
+
+// виды отметок в журнале: выполнение, пропуск повторения и перенос повторения на другую дату
+const (
+	CompletionDone    = "done"
+	CompletionSkipped = "skipped"
+	CompletionSnoozed = "snoozed"
+)
+
+// Completion - отметка о выполнении, пропуске или переносе задачи, соответствует записям в таблице completions
+// Title - заголовок задачи на момент отметки, задача может быть позже изменена или удалена
+type Completion struct {
+	ID       string `json:"id"`
+	TaskID   string `json:"task_id"`
+	Kind     string `json:"kind"`
+	Title    string `json:"title"`
+	Date     string `json:"date"`
+	DoneAt   string `json:"done_at"`
+	NextDate string `json:"next_date,omitempty"`
+}
+
+// Complete записывает отметку о выполнении, пропуске или переносе задачи и в той же транзакции
+// удаляет задачу completion.TaskID, если next равно nil, или сохраняет next - задачу, перенесенную на новую дату
+// Если undo задан, прежнее состояние задачи сохраняется для отмены по токену
+func (s *SQLStore) Complete(userID int64, completion *Completion, next *Task, undo *Undo) error {
+	if _, ok := parseID(completion.TaskID); !ok {
+		return ErrTaskNotFound
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var previous *Task
+	if undo != nil {
+		if previous, err = scanTask(tx.QueryRow(s.rebind(selectTaskQuery), completion.TaskID, userID)); err != nil {
+			return err
+		}
+	}
+	if next == nil {
+		err = s.txExecAffected(tx, ErrTaskNotFound, "error deleting task",
+			`DELETE FROM scheduler WHERE id = ? AND user_id = ? AND deleted_at = ''`, completion.TaskID, userID)
+	} else {
+		err = s.txExecAffected(tx, ErrTaskNotFound, "error updating task", updateTaskQuery, updateTaskArgs(userID, next)...)
+	}
+	if err != nil {
+		return err
+	}
+
+	if completion.Kind == "" {
+		completion.Kind = CompletionDone
+	}
+	query := `INSERT INTO completions (user_id, task_id, kind, title, date, done_at, next_date)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`
+	id, err := s.txInsert(tx, query, userID, completion.TaskID, completion.Kind, completion.Title, completion.Date,
+		completion.DoneAt, completion.NextDate)
+	if err != nil {
+		return fmt.Errorf("error saving completion: %w", err)
+	}
+	if undo != nil {
+		if err := s.saveUndo(tx, userID, previous, undo, id); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error saving completion: %w", err)
+	}
+	completion.ID = fmt.Sprint(id)
+	return nil
+}
+
+// TaskHistory получает отметки о выполнении, пропуске и переносе задачи в порядке их записи
+func (s *SQLStore) TaskHistory(userID int64, taskID string) ([]*Completion, error) {
+	if _, ok := parseID(taskID); !ok {
+		return nil, nil
+	}
+	query := `SELECT id, task_id, kind, title, date, done_at, next_date FROM completions
+			WHERE user_id = ? AND task_id = ? ORDER BY done_at ASC, id ASC`
+	return s.completions(query, userID, taskID)
+}
+
+// Completions получает отметки задач пользователя с from включительно до to,
+// упорядоченные по времени записи
+func (s *SQLStore) Completions(userID int64, from, to time.Time) ([]*Completion, error) {
+	query := `SELECT id, task_id, kind, title, date, done_at, next_date FROM completions
+			WHERE user_id = ? AND done_at >= ? AND done_at < ? ORDER BY done_at ASC, id ASC`
+	return s.completions(query, userID, from.UTC().Format(TimestampFormat), to.UTC().Format(TimestampFormat))
+}
+
+// completions выполняет запрос списка отметок журнала
+func (s *SQLStore) completions(query string, args ...any) ([]*Completion, error) {
+	rows, err := s.query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("SQL query error: %w", err)
+	}
+	defer rows.Close()
+
+	var completions []*Completion
+	for rows.Next() {
+		c := &Completion{}
+		if err := rows.Scan(&c.ID, &c.TaskID, &c.Kind, &c.Title, &c.Date, &c.DoneAt, &c.NextDate); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		completions = append(completions, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error processing result: %w", err)
+	}
+	return completions, nil
+}
@@ -3,41 +3,167 @@ package db
 import (
 	"database/sql"
 	"fmt"
-	"os"
+	"strconv"
+	"strings"
 
+	_ "github.com/lib/pq"
 	_ "modernc.org/sqlite"
 )
 
-var DB *sql.DB
-
-// schema - скрипт для создания БД, создает таблицу задач и индекс для поиска по времени
-const schema = `CREATE TABLE scheduler (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    date CHAR(8) NOT NULL DEFAULT "",
-    title VARCHAR(255) NOT NULL DEFAULT "",
-	comment TEXT,
-	repeat VARCHAR(128) NOT NULL DEFAULT "");
-	CREATE INDEX IF NOT EXISTS idx_date ON scheduler(date);`
-
-// Init инициализирует соединение с БД, создает файл БД, если такой не существует
-func Init(dbFile string) error {
-	_, err := os.Stat(dbFile)
-	install := os.IsNotExist(err)
-	
-	if err != nil && !os.IsNotExist(err) {
-		return err
-	}
-	
-	DB, err = sql.Open("sqlite", dbFile)
-		if err != nil {
-			return fmt.Errorf("DB open error: %w", err)
-		}
-	if install {
-		_, err = DB.Exec(schema)
-		if err != nil {
-			DB.Close() // при ошибке закрываем соединение
-			return fmt.Errorf("DB creation error: %w", err)
+// драйверы БД, поддерживаемые хранилищем
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// SQLStore - реализация хранилища на SQL-БД: SQLite или PostgreSQL
+// Запросы записываются с параметрами "?" и приводятся к синтаксису драйвера при выполнении
+type SQLStore struct {
+	db     *sql.DB
+	driver string
+}
+
+// NewStore открывает БД выбранного драйвера и приводит ее схему к актуальной версии
+// для SQLite в качестве dsn передается путь к файлу БД, файл создается, если такой не существует
+func NewStore(driver, dsn string) (*SQLStore, error) {
+	s, err := OpenStore(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Migrate(); err != nil {
+		s.Close() // при ошибке закрываем соединение
+		return nil, fmt.Errorf("DB migration error: %w", err)
+	}
+	return s, nil
+}
+
+// NewSQLiteStore открывает БД SQLite и приводит ее схему к актуальной версии
+func NewSQLiteStore(dbFile string) (*SQLStore, error) {
+	return NewStore(DriverSQLite, dbFile)
+}
+
+// NewPostgresStore подключается к PostgreSQL и приводит схему БД к актуальной версии
+func NewPostgresStore(dsn string) (*SQLStore, error) {
+	return NewStore(DriverPostgres, dsn)
+}
+
+// OpenStore открывает соединение с БД без применения миграций
+func OpenStore(driver, dsn string) (*SQLStore, error) {
+	if driver != DriverSQLite && driver != DriverPostgres {
+		return nil, fmt.Errorf("unsupported DB driver: %s", driver)
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("DB open error: %w", err)
+	}
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("DB open error: %w", err)
+	}
+	return &SQLStore{db: db, driver: driver}, nil
+}
+
+// Driver возвращает имя драйвера БД
+func (s *SQLStore) Driver() string {
+	return s.driver
+}
+
+// Close закрывает соединение с БД
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// rebind заменяет параметры "?" на нумерованные параметры PostgreSQL
+func (s *SQLStore) rebind(query string) string {
+	if s.driver != DriverPostgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
 		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// exec выполняет запрос на изменение данных
+func (s *SQLStore) exec(query string, args ...any) (sql.Result, error) {
+	return s.db.Exec(s.rebind(query), args...)
+}
+
+// query выполняет запрос, возвращающий набор строк
+func (s *SQLStore) query(query string, args ...any) (*sql.Rows, error) {
+	return s.db.Query(s.rebind(query), args...)
+}
+
+// queryRow выполняет запрос, возвращающий одну строку
+func (s *SQLStore) queryRow(query string, args ...any) *sql.Row {
+	return s.db.QueryRow(s.rebind(query), args...)
+}
+
+// insert выполняет INSERT и возвращает id добавленной записи
+// PostgreSQL не поддерживает LastInsertId, поэтому id получаем через RETURNING
+func (s *SQLStore) insert(query string, args ...any) (int64, error) {
+	if s.driver == DriverPostgres {
+		var id int64
+		err := s.queryRow(query+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+	res, err := s.exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// execAffected выполняет запрос на изменение и проверяет, что он затронул хотя бы одну запись,
+// иначе возвращает ошибку notFound
+func (s *SQLStore) execAffected(notFound error, errPrefix, query string, args ...any) error {
+	res, err := s.exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errPrefix, err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting affected rows: %w", err)
+	}
+	if count == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// txInsert выполняет INSERT в транзакции tx и возвращает id добавленной записи
+func (s *SQLStore) txInsert(tx *sql.Tx, query string, args ...any) (int64, error) {
+	if s.driver == DriverPostgres {
+		var id int64
+		err := tx.QueryRow(s.rebind(query+" RETURNING id"), args...).Scan(&id)
+		return id, err
+	}
+	res, err := tx.Exec(s.rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// txExecAffected выполняет запрос на изменение в транзакции tx, как execAffected
+func (s *SQLStore) txExecAffected(tx *sql.Tx, notFound error, errPrefix, query string, args ...any) error {
+	res, err := tx.Exec(s.rebind(query), args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errPrefix, err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting affected rows: %w", err)
+	}
+	if count == 0 {
+		return notFound
 	}
 	return nil
 }
\ No newline at end of file
@@ -0,0 +1,226 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FilterOp - вид узла условия структурированного поиска
+type FilterOp int
+
+const (
+	FilterTerm FilterOp = iota // условие на поле задачи
+	FilterAnd                  // все операнды истинны
+	FilterOr                   // хотя бы один операнд истинен
+	FilterNot                  // единственный операнд ложен
+)
+
+// поля, по которым строятся условия структурированного поиска
+const (
+	FieldText    = "text"    // слово или фраза в заголовке или комментарии
+	FieldTitle   = "title"   // слово или фраза в заголовке
+	FieldComment = "comment" // слово или фраза в комментарии
+	FieldRepeat  = "repeat"  // тип правила повторения: none, any или буква правила
+	FieldBefore  = "before"  // дата раньше указанной
+	FieldAfter   = "after"   // дата позже указанной
+	FieldOn      = "on"      // дата совпадает с указанной
+	FieldFrom    = "from"    // дата не раньше указанной
+	FieldTo      = "to"      // дата не позже указанной
+)
+
+// значения условия на поле repeat, не являющиеся буквой правила: none - без правила, any - любое правило,
+// в том числе RRULE
+const (
+	RepeatNone = "none"
+	RepeatAny  = "any"
+)
+
+// rruleFreqs - частота правил RRULE, соответствующая букве правила: задачи с такими правилами
+// хранятся в записи FREQ=<частота>;... и тоже находятся по условию repeat:<буква>
+var rruleFreqs = map[string]string{"d": "DAILY", "w": "WEEKLY", "m": "MONTHLY", "y": "YEARLY"}
+
+// Filter - условие структурированного поиска задач: логическое выражение над условиями на поля
+// Даты в условиях указываются в формате DateFormat
+type Filter struct {
+	Op       FilterOp
+	Children []*Filter // операнды FilterAnd, FilterOr и FilterNot
+	Field    string    // поле условия FilterTerm
+	Value    string    // значение условия FilterTerm
+	Phrase   bool      // текст ищется как точная фраза, а не по префиксу последнего слова
+}
+
+// And объединяет условия по И, nil-условия пропускаются
+func And(filters ...*Filter) *Filter {
+	var children []*Filter
+	for _, f := range filters {
+		if f != nil {
+			children = append(children, f)
+		}
+	}
+	switch len(children) {
+	case 0:
+		return nil
+	case 1:
+		return children[0]
+	}
+	return &Filter{Op: FilterAnd, Children: children}
+}
+
+// textColumns возвращает колонки, в которых ищется текст условия
+func (f *Filter) textColumns() []string {
+	switch f.Field {
+	case FieldTitle:
+		return []string{"title"}
+	case FieldComment:
+		return []string{"comment"}
+	default:
+		return []string{"title", "comment"}
+	}
+}
+
+// searchTerm возвращает условие полнотекстового поиска для текстового условия
+func (f *Filter) searchTerm() searchTerm {
+	return searchTerm{tokens: tokenize(f.Value), prefix: !f.Phrase}
+}
+
+// filterSQL строит параметризованное SQL-условие на колонки задачи для запроса к таблице scheduler
+func (s *SQLStore) filterSQL(f *Filter) (string, []any, error) {
+	switch f.Op {
+	case FilterAnd, FilterOr:
+		if len(f.Children) == 0 {
+			return "", nil, fmt.Errorf("empty filter group")
+		}
+		sep := " AND "
+		if f.Op == FilterOr {
+			sep = " OR "
+		}
+		parts := make([]string, 0, len(f.Children))
+		var args []any
+		for _, child := range f.Children {
+			part, childArgs, err := s.filterSQL(child)
+			if err != nil {
+				return "", nil, err
+			}
+			parts = append(parts, part)
+			args = append(args, childArgs...)
+		}
+		return "(" + strings.Join(parts, sep) + ")", args, nil
+	case FilterNot:
+		if len(f.Children) != 1 {
+			return "", nil, fmt.Errorf("negation requires one operand")
+		}
+		part, args, err := s.filterSQL(f.Children[0])
+		if err != nil {
+			return "", nil, err
+		}
+		return "NOT " + part, args, nil
+	}
+
+	switch f.Field {
+	case FieldText, FieldTitle, FieldComment:
+		term := f.searchTerm()
+		if len(term.tokens) == 0 {
+			return "", nil, fmt.Errorf("empty text in filter")
+		}
+		columns := f.textColumns()
+		if s.driver == DriverPostgres {
+			expr := "title || ' ' || COALESCE(comment, '')"
+			if len(columns) == 1 {
+				expr = "COALESCE(" + columns[0] + ", '')"
+				if columns[0] == "title" {
+					expr = "title"
+				}
+			}
+			return "(to_tsvector('simple', " + expr + ") @@ to_tsquery('simple', ?))",
+				[]any{tsQuery([]searchTerm{term})}, nil
+		}
+		match := ftsQuery([]searchTerm{term})
+		if len(columns) == 1 {
+			match = columns[0] + " : " + match
+		}
+		return "(id IN (SELECT rowid FROM scheduler_fts WHERE scheduler_fts MATCH ?))", []any{match}, nil
+	case FieldRepeat:
+		switch f.Value {
+		case RepeatNone:
+			return "(repeat = '')", nil, nil
+		case RepeatAny:
+			return "(repeat <> '')", nil, nil
+		}
+		if freq, ok := rruleFreqs[f.Value]; ok {
+			return "(repeat = ? OR repeat LIKE ? OR repeat = ? OR repeat LIKE ?)",
+				[]any{f.Value, f.Value + " %", "FREQ=" + freq, "FREQ=" + freq + ";%"}, nil
+		}
+		return "(repeat = ? OR repeat LIKE ?)", []any{f.Value, f.Value + " %"}, nil
+	case FieldBefore:
+		return "(date < ?)", []any{f.Value}, nil
+	case FieldAfter:
+		return "(date > ?)", []any{f.Value}, nil
+	case FieldOn:
+		return "(date = ?)", []any{f.Value}, nil
+	case FieldFrom:
+		return "(date >= ?)", []any{f.Value}, nil
+	case FieldTo:
+		return "(date <= ?)", []any{f.Value}, nil
+	}
+	return "", nil, fmt.Errorf("unknown filter field: %s", f.Field)
+}
+
+// Match проверяет задачу на соответствие условию, используется хранилищем в памяти
+func (f *Filter) Match(task *Task) bool {
+	switch f.Op {
+	case FilterAnd:
+		for _, child := range f.Children {
+			if !child.Match(task) {
+				return false
+			}
+		}
+		return true
+	case FilterOr:
+		for _, child := range f.Children {
+			if child.Match(task) {
+				return true
+			}
+		}
+		return false
+	case FilterNot:
+		return len(f.Children) == 1 && !f.Children[0].Match(task)
+	}
+
+	switch f.Field {
+	case FieldText, FieldTitle, FieldComment:
+		terms := []searchTerm{f.searchTerm()}
+		for _, column := range f.textColumns() {
+			text := task.Title
+			if column == "comment" {
+				text = task.Comment
+			}
+			if found, _ := matchText(text, terms); found[0] {
+				return true
+			}
+		}
+		return false
+	case FieldRepeat:
+		switch f.Value {
+		case RepeatNone:
+			return task.Repeat == ""
+		case RepeatAny:
+			return task.Repeat != ""
+		}
+		if task.Repeat == f.Value || strings.HasPrefix(task.Repeat, f.Value+" ") {
+			return true
+		}
+		freq, ok := rruleFreqs[f.Value]
+		return ok && (task.Repeat == "FREQ="+freq || strings.HasPrefix(task.Repeat, "FREQ="+freq+";"))
+	case FieldBefore:
+		return task.Date < f.Value
+	case FieldAfter:
+		return task.Date > f.Value
+	case FieldOn:
+		return task.Date == f.Value
+	case FieldFrom:
+		return task.Date >= f.Value
+	case FieldTo:
+		return task.Date <= f.Value
+	}
+	return false
+}
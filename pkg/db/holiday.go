@@ -0,0 +1,72 @@
+package db
+
+import (
+	"fmt"
+)
+
+// Holiday - день календаря праздников, соответствует записям в таблице holidays
+// Working - перенесенный рабочий день, иначе день нерабочий
+type Holiday struct {
+	Date    string `json:"date"`
+	Name    string `json:"name"`
+	Working bool   `json:"working"`
+}
+
+// SetHolidays добавляет дни в календарь пользователя в одной транзакции,
+// существующие дни с теми же датами заменяются
+func (s *SQLStore) SetHolidays(userID int64, holidays []*Holiday) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := s.rebind(`INSERT INTO holidays (user_id, date, name, working) VALUES (?, ?, ?, ?)
+			ON CONFLICT (user_id, date) DO UPDATE SET name = excluded.name, working = excluded.working`)
+	for _, h := range holidays {
+		if _, err := tx.Exec(query, userID, h.Date, h.Name, h.Working); err != nil {
+			return fmt.Errorf("error saving holiday: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Holidays получает дни календаря пользователя в периоде from - to включительно, упорядоченные по дате
+// Пустая граница не ограничивает период
+func (s *SQLStore) Holidays(userID int64, from, to string) ([]*Holiday, error) {
+	query := `SELECT date, name, working FROM holidays WHERE user_id = ?`
+	args := []any{userID}
+	if from != "" {
+		query += ` AND date >= ?`
+		args = append(args, from)
+	}
+	if to != "" {
+		query += ` AND date <= ?`
+		args = append(args, to)
+	}
+
+	rows, err := s.query(query+` ORDER BY date ASC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("SQL query error: %w", err)
+	}
+	defer rows.Close()
+
+	var holidays []*Holiday
+	for rows.Next() {
+		h := &Holiday{}
+		if err := rows.Scan(&h.Date, &h.Name, &h.Working); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		holidays = append(holidays, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error processing result: %w", err)
+	}
+	return holidays, nil
+}
+
+// DeleteHoliday удаляет день из календаря пользователя
+func (s *SQLStore) DeleteHoliday(userID int64, date string) error {
+	query := `DELETE FROM holidays WHERE user_id = ? AND date = ?`
+	return s.execAffected(ErrHolidayNotFound, "error deleting holiday", query, userID, date)
+}
@@ -0,0 +1,31 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// ServerKey получает секретный ключ сервера name, а если его еще нет - сохраняет значение, созданное generate
+// Если ключ одновременно создают несколько экземпляров сервера, все они получают ключ, сохраненный первым
+func (s *SQLStore) ServerKey(name string, generate func() (string, error)) (string, error) {
+	var value string
+	err := s.queryRow(`SELECT value FROM server_keys WHERE name = ?`, name).Scan(&value)
+	if err == nil {
+		return value, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", fmt.Errorf("error getting server key: %w", err)
+	}
+
+	if value, err = generate(); err != nil {
+		return "", err
+	}
+	query := `INSERT INTO server_keys (name, value) VALUES (?, ?) ON CONFLICT (name) DO NOTHING`
+	if _, err := s.exec(query, name, value); err != nil {
+		return "", fmt.Errorf("error saving server key: %w", err)
+	}
+	if err := s.queryRow(`SELECT value FROM server_keys WHERE name = ?`, name).Scan(&value); err != nil {
+		return "", fmt.Errorf("error getting server key: %w", err)
+	}
+	return value, nil
+}
@@ -0,0 +1,638 @@
+package db
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// memoryTask - задача в памяти вместе с id ее владельца
+type memoryTask struct {
+	Task
+	userID int64
+}
+
+// memoryToken - API-токен в памяти вместе с хэшем его значения
+type memoryToken struct {
+	APIToken
+	hash string
+}
+
+// memoryCompletion - отметка о выполнении в памяти вместе с id владельца задачи
+type memoryCompletion struct {
+	Completion
+	userID int64
+}
+
+// memoryUndo - отметка отмены в памяти: состояние задачи до операции и id отметки о выполнении
+type memoryUndo struct {
+	Undo
+	userID       int64
+	task         Task
+	completionID string
+}
+
+// MemoryStore - реализация хранилища в памяти процесса,
+// используется в тестах и для запуска без файла БД
+type MemoryStore struct {
+	mu          sync.RWMutex
+	tasks       map[int64]*memoryTask
+	users       map[int64]*User
+	tokens      map[int64]*memoryToken
+	keys        map[string]string
+	holidays    map[int64]map[string]Holiday
+	timezones   map[int64]string
+	completions []*memoryCompletion
+	undo        map[string]*memoryUndo
+	lastTaskID  int64
+	lastUserID  int64
+	lastTokenID int64
+	lastDoneID  int64
+}
+
+// NewMemoryStore создает пустое хранилище в памяти
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		tasks:     make(map[int64]*memoryTask),
+		users:     make(map[int64]*User),
+		tokens:    make(map[int64]*memoryToken),
+		keys:      make(map[string]string),
+		holidays:  make(map[int64]map[string]Holiday),
+		timezones: make(map[int64]string),
+		undo:      make(map[string]*memoryUndo),
+	}
+}
+
+// Close освобождает хранилище, данные в памяти при этом не сохраняются
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+// Add добавляет новую задачу пользователя, возвращает ее id
+func (m *MemoryStore) Add(userID int64, task *Task) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastTaskID++
+	stored := &memoryTask{Task: *task, userID: userID}
+	stored.ID = strconv.FormatInt(m.lastTaskID, 10)
+	stored.SnoozedFrom, stored.DeletedAt = "", ""
+	m.tasks[m.lastTaskID] = stored
+	return m.lastTaskID, nil
+}
+
+// Get получает задачу пользователя по ее id
+func (m *MemoryStore) Get(userID int64, id string) (*Task, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stored, err := m.task(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	task := stored.Task
+	return &task, nil
+}
+
+// List получает страницу задач пользователя, упорядоченных по дате
+func (m *MemoryStore) List(userID int64, page Page) (*TaskPage, error) {
+	return m.Find(userID, page, nil)
+}
+
+// Search получает страницу задач пользователя с возможностью поиска по дате или тексту,
+// дополнительно ограниченных условием filter, если оно задано
+func (m *MemoryStore) Search(userID int64, page Page, search string, filter *Filter) (*TaskPage, error) {
+	if searchDate, err := time.Parse("02.01.2006", search); err == nil {
+		return m.Find(userID, page, And(&Filter{Field: FieldOn, Value: searchDate.Format(DateFormat)}, filter))
+	}
+
+	terms := parseSearch(search)
+	if len(terms) == 0 {
+		return &TaskPage{}, nil
+	}
+
+	// как и в полнотекстовом индексе, каждое условие должно найтись в заголовке или комментарии,
+	// совпадения в заголовке весят больше
+	ranks := make(map[string]int)
+	tasks := m.filter(userID, func(t *Task) bool {
+		if filter != nil && !filter.Match(t) {
+			return false
+		}
+		inTitle, markedTitle := matchText(t.Title, terms)
+		inComment, markedComment := matchText(t.Comment, terms)
+		rank := 0
+		for i := range terms {
+			if !inTitle[i] && !inComment[i] {
+				return false
+			}
+			if inTitle[i] {
+				rank += 2
+			}
+			if inComment[i] {
+				rank++
+			}
+		}
+		ranks[t.ID] = rank
+		if markedTitle != "" {
+			t.Snippet = highlight(markedTitle)
+		} else {
+			t.Snippet = highlight(markedComment)
+		}
+		return true
+	})
+	return paginate(tasks, func(t *Task) int { return ranks[t.ID] }, page), nil
+}
+
+// Find получает страницу задач пользователя, подходящих под условие структурированного поиска,
+// nil-условие соответствует всем задачам
+func (m *MemoryStore) Find(userID int64, page Page, filter *Filter) (*TaskPage, error) {
+	tasks := m.filter(userID, func(t *Task) bool { return filter == nil || filter.Match(t) })
+	return paginate(tasks, func(*Task) int { return 0 }, page), nil
+}
+
+// Update обновляет существующую задачу пользователя
+func (m *MemoryStore) Update(userID int64, task *Task) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, err := m.task(userID, task.ID)
+	if err != nil {
+		return err
+	}
+	stored.update(task)
+	return nil
+}
+
+// update копирует в задачу изменяемые поля task
+func (t *memoryTask) update(task *Task) {
+	t.Date, t.Title, t.Comment, t.Repeat = task.Date, task.Title, task.Comment, task.Repeat
+	t.Until, t.Remaining = task.Until, task.Remaining
+	t.Time, t.Duration, t.Timezone = task.Time, task.Duration, task.Timezone
+	t.SnoozedFrom = task.SnoozedFrom
+}
+
+// Delete перемещает задачу пользователя в корзину по ее id
+func (m *MemoryStore) Delete(userID int64, id string, undo *Undo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, err := m.task(userID, id)
+	if err != nil {
+		return err
+	}
+	if undo != nil {
+		m.saveUndo(userID, stored.Task, undo, "")
+	}
+	stored.DeletedAt = time.Now().UTC().Format(TimestampFormat)
+	return nil
+}
+
+// UpdateDate переносит задачу пользователя на новую дату
+func (m *MemoryStore) UpdateDate(userID int64, nextDate string, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, err := m.task(userID, id)
+	if err != nil {
+		return err
+	}
+	stored.Date = nextDate
+	return nil
+}
+
+// task находит задачу пользователя не из корзины, вызывается под блокировкой
+func (m *MemoryStore) task(userID int64, id string) (*memoryTask, error) {
+	stored, err := m.anyTask(userID, id)
+	if err != nil || stored.DeletedAt != "" {
+		return nil, ErrTaskNotFound
+	}
+	return stored, nil
+}
+
+// anyTask находит задачу пользователя, в том числе из корзины, вызывается под блокировкой
+func (m *MemoryStore) anyTask(userID int64, id string) (*memoryTask, error) {
+	n, ok := parseID(id)
+	if !ok {
+		return nil, ErrTaskNotFound
+	}
+	stored, ok := m.tasks[n]
+	if !ok || stored.userID != userID {
+		return nil, ErrTaskNotFound
+	}
+	return stored, nil
+}
+
+// filter возвращает копии задач пользователя не из корзины, подходящих под условие
+// условие получает копию задачи и может дополнить ее, например сниппетом
+func (m *MemoryStore) filter(userID int64, match func(*Task) bool) []*Task {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var tasks []*Task
+	for _, stored := range m.tasks {
+		if stored.userID != userID || stored.DeletedAt != "" {
+			continue
+		}
+		task := stored.Task
+		if !match(&task) {
+			continue
+		}
+		tasks = append(tasks, &task)
+	}
+	return tasks
+}
+
+// paginate упорядочивает задачи по убыванию ранга, дате и id и выбирает страницу после курсора
+func paginate(tasks []*Task, rank func(*Task) int, page Page) *TaskPage {
+	cursor := func(t *Task) Cursor {
+		id, _ := parseID(t.ID)
+		return Cursor{Rank: rank(t), Date: t.Date, ID: id}
+	}
+	less := func(a, b Cursor) bool {
+		if a.Rank != b.Rank {
+			return a.Rank > b.Rank
+		}
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		return a.ID < b.ID
+	}
+	sort.Slice(tasks, func(i, j int) bool { return less(cursor(tasks[i]), cursor(tasks[j])) })
+
+	result := &TaskPage{Total: len(tasks)}
+	for _, task := range tasks {
+		if page.After != nil && !less(*page.After, cursor(task)) {
+			continue
+		}
+		if len(result.Tasks) == page.Limit {
+			if page.Limit == 0 {
+				break
+			}
+			next := cursor(result.Tasks[len(result.Tasks)-1])
+			result.Next = &next
+			break
+		}
+		result.Tasks = append(result.Tasks, task)
+	}
+	return result
+}
+
+// Trash получает задачи пользователя из корзины, сначала удаленные последними
+func (m *MemoryStore) Trash(userID int64) ([]*Task, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var tasks []*Task
+	for _, stored := range m.tasks {
+		if stored.userID == userID && stored.DeletedAt != "" {
+			task := stored.Task
+			tasks = append(tasks, &task)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].DeletedAt != tasks[j].DeletedAt {
+			return tasks[i].DeletedAt > tasks[j].DeletedAt
+		}
+		a, _ := parseID(tasks[i].ID)
+		b, _ := parseID(tasks[j].ID)
+		return a > b
+	})
+	return tasks, nil
+}
+
+// RestoreTask возвращает задачу пользователя из корзины в список задач
+func (m *MemoryStore) RestoreTask(userID int64, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, err := m.anyTask(userID, id)
+	if err != nil || stored.DeletedAt == "" {
+		return ErrTaskNotFound
+	}
+	stored.DeletedAt = ""
+	return nil
+}
+
+// EmptyTrash окончательно удаляет все задачи пользователя из корзины, возвращает их количество
+func (m *MemoryStore) EmptyTrash(userID int64) (int64, error) {
+	return m.deleteTrashed(func(t *memoryTask) bool { return t.userID == userID }), nil
+}
+
+// PurgeTrash окончательно удаляет задачи всех пользователей, попавшие в корзину раньше before
+func (m *MemoryStore) PurgeTrash(before time.Time) (int64, error) {
+	limit := before.UTC().Format(TimestampFormat)
+	return m.deleteTrashed(func(t *memoryTask) bool { return t.DeletedAt < limit }), nil
+}
+
+// deleteTrashed удаляет из корзины задачи, подходящие под условие, возвращает их количество
+func (m *MemoryStore) deleteTrashed(match func(*memoryTask) bool) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var count int64
+	for id, stored := range m.tasks {
+		if stored.DeletedAt != "" && match(stored) {
+			delete(m.tasks, id)
+			count++
+		}
+	}
+	return count
+}
+
+// AddUser добавляет пользователя, возвращает его id
+func (m *MemoryStore) AddUser(login, passwordHash string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, user := range m.users {
+		if user.Login == login {
+			return 0, ErrUserExists
+		}
+	}
+	m.lastUserID++
+	m.users[m.lastUserID] = &User{ID: m.lastUserID, Login: login, PasswordHash: passwordHash}
+	return m.lastUserID, nil
+}
+
+// GetUser получает пользователя по id
+func (m *MemoryStore) GetUser(id int64) (*User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	found := *user
+	return &found, nil
+}
+
+// GetUserByLogin получает пользователя по логину
+func (m *MemoryStore) GetUserByLogin(login string) (*User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, user := range m.users {
+		if user.Login == login {
+			found := *user
+			return &found, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+// AddToken сохраняет API-токен пользователя по хэшу его значения, возвращает id токена
+func (m *MemoryStore) AddToken(userID int64, token *APIToken, hash string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastTokenID++
+	stored := &memoryToken{APIToken: *token, hash: hash}
+	stored.ID = strconv.FormatInt(m.lastTokenID, 10)
+	stored.UserID = userID
+	m.tokens[m.lastTokenID] = stored
+	return m.lastTokenID, nil
+}
+
+// Tokens получает список API-токенов пользователя
+func (m *MemoryStore) Tokens(userID int64) ([]*APIToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var tokens []*APIToken
+	for id := int64(1); id <= m.lastTokenID; id++ {
+		if stored, ok := m.tokens[id]; ok && stored.UserID == userID {
+			token := stored.APIToken
+			tokens = append(tokens, &token)
+		}
+	}
+	return tokens, nil
+}
+
+// TokenByHash получает API-токен по хэшу его значения
+func (m *MemoryStore) TokenByHash(hash string) (*APIToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, stored := range m.tokens {
+		if stored.hash == hash {
+			token := stored.APIToken
+			return &token, nil
+		}
+	}
+	return nil, ErrTokenNotFound
+}
+
+// RenameToken изменяет название API-токена пользователя
+func (m *MemoryStore) RenameToken(userID int64, id string, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, err := m.token(id)
+	if err != nil || stored.UserID != userID {
+		return ErrTokenNotFound
+	}
+	stored.Name = name
+	return nil
+}
+
+// TouchToken сохраняет время последнего использования API-токена
+func (m *MemoryStore) TouchToken(id string, usedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, err := m.token(id)
+	if err != nil {
+		return err
+	}
+	stored.LastUsedAt = usedAt.UTC().Format(time.RFC3339)
+	return nil
+}
+
+// DeleteToken отзывает API-токен пользователя
+func (m *MemoryStore) DeleteToken(userID int64, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, err := m.token(id)
+	if err != nil || stored.UserID != userID {
+		return ErrTokenNotFound
+	}
+	n, _ := parseID(id)
+	delete(m.tokens, n)
+	return nil
+}
+
+// token находит API-токен по id, вызывается под блокировкой
+func (m *MemoryStore) token(id string) (*memoryToken, error) {
+	n, ok := parseID(id)
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	stored, ok := m.tokens[n]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	return stored, nil
+}
+
+// ServerKey получает секретный ключ сервера, а если его еще нет - сохраняет значение, созданное generate
+func (m *MemoryStore) ServerKey(name string, generate func() (string, error)) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if value, ok := m.keys[name]; ok {
+		return value, nil
+	}
+	value, err := generate()
+	if err != nil {
+		return "", err
+	}
+	m.keys[name] = value
+	return value, nil
+}
+
+// SetHolidays добавляет дни в календарь пользователя, существующие дни с теми же датами заменяются
+func (m *MemoryStore) SetHolidays(userID int64, holidays []*Holiday) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.holidays[userID] == nil {
+		m.holidays[userID] = make(map[string]Holiday)
+	}
+	for _, h := range holidays {
+		m.holidays[userID][h.Date] = *h
+	}
+	return nil
+}
+
+// Holidays получает дни календаря пользователя в периоде from - to включительно, упорядоченные по дате
+func (m *MemoryStore) Holidays(userID int64, from, to string) ([]*Holiday, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var holidays []*Holiday
+	for date, h := range m.holidays[userID] {
+		if (from == "" || date >= from) && (to == "" || date <= to) {
+			found := h
+			holidays = append(holidays, &found)
+		}
+	}
+	sort.Slice(holidays, func(i, j int) bool { return holidays[i].Date < holidays[j].Date })
+	return holidays, nil
+}
+
+// DeleteHoliday удаляет день из календаря пользователя
+func (m *MemoryStore) DeleteHoliday(userID int64, date string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.holidays[userID][date]; !ok {
+		return ErrHolidayNotFound
+	}
+	delete(m.holidays[userID], date)
+	return nil
+}
+
+// Timezone получает часовой пояс пользователя, пустая строка - часовой пояс сервера
+func (m *MemoryStore) Timezone(userID int64) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.timezones[userID], nil
+}
+
+// SetTimezone сохраняет часовой пояс пользователя
+func (m *MemoryStore) SetTimezone(userID int64, tz string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.timezones[userID] = tz
+	return nil
+}
+
+// Complete записывает отметку журнала и удаляет задачу или сохраняет ее с новой датой
+func (m *MemoryStore) Complete(userID int64, completion *Completion, next *Task, undo *Undo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, err := m.task(userID, completion.TaskID)
+	if err != nil {
+		return err
+	}
+	m.lastDoneID++
+	completion.ID = strconv.FormatInt(m.lastDoneID, 10)
+	if completion.Kind == "" {
+		completion.Kind = CompletionDone
+	}
+	if undo != nil {
+		m.saveUndo(userID, stored.Task, undo, completion.ID)
+	}
+	if next == nil {
+		id, _ := parseID(completion.TaskID)
+		delete(m.tasks, id)
+	} else {
+		stored.update(next)
+	}
+	m.completions = append(m.completions, &memoryCompletion{Completion: *completion, userID: userID})
+	return nil
+}
+
+// TaskHistory получает отметки журнала по задаче в порядке их записи
+func (m *MemoryStore) TaskHistory(userID int64, taskID string) ([]*Completion, error) {
+	return m.findCompletions(userID, func(c *Completion) bool { return c.TaskID == taskID }), nil
+}
+
+// Completions получает отметки журнала с from включительно до to в порядке их записи
+func (m *MemoryStore) Completions(userID int64, from, to time.Time) ([]*Completion, error) {
+	start, end := from.UTC().Format(TimestampFormat), to.UTC().Format(TimestampFormat)
+	return m.findCompletions(userID, func(c *Completion) bool { return c.DoneAt >= start && c.DoneAt < end }), nil
+}
+
+// findCompletions возвращает копии отметок пользователя, подходящих под условие, в порядке выполнения
+func (m *MemoryStore) findCompletions(userID int64, match func(*Completion) bool) []*Completion {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var completions []*Completion
+	for _, c := range m.completions {
+		if c.userID == userID && match(&c.Completion) {
+			found := c.Completion
+			completions = append(completions, &found)
+		}
+	}
+	sort.SliceStable(completions, func(i, j int) bool { return completions[i].DoneAt < completions[j].DoneAt })
+	return completions
+}
+
+// saveUndo сохраняет состояние задачи до операции для отмены, просроченные отметки удаляются
+func (m *MemoryStore) saveUndo(userID int64, task Task, undo *Undo, completionID string) {
+	now := time.Now().UTC().Format(TimestampFormat)
+	for token, u := range m.undo {
+		if u.ExpiresAt <= now {
+			delete(m.undo, token)
+		}
+	}
+	m.undo[undo.Token] = &memoryUndo{Undo: *undo, userID: userID, task: task, completionID: completionID}
+}
+
+// Undo отменяет удаление или выполнение задачи по токену, действующему на момент now
+func (m *MemoryStore) Undo(userID int64, token string, now time.Time) (*Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.undo[token]
+	if !ok || u.userID != userID || u.ExpiresAt <= now.UTC().Format(TimestampFormat) {
+		return nil, ErrUndoNotFound
+	}
+	id, _ := parseID(u.task.ID)
+	m.tasks[id] = &memoryTask{Task: u.task, userID: userID}
+	if u.completionID != "" {
+		m.completions = slices.DeleteFunc(m.completions, func(c *memoryCompletion) bool {
+			return c.ID == u.completionID
+		})
+	}
+	delete(m.undo, token)
+	task := u.task
+	return &task, nil
+}
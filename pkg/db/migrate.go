@@ -0,0 +1,232 @@
+package db
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// migrationFiles - встроенные в бинарный файл миграции схемы БД, отдельные для каждого драйвера
+// имя файла имеет вид migrations/<драйвер>/NNNN_name.sql, где NNNN - номер версии схемы
+//
+//go:embed migrations/*/*.sql
+var migrationFiles embed.FS
+
+// ErrSchemaTooNew - ошибка запуска с БД, схема которой новее известной приложению
+var ErrSchemaTooNew = errors.New("database schema is newer than supported")
+
+// Migration - миграция схемы БД
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// MigrationStatus - состояние миграции в БД
+type MigrationStatus struct {
+	Migration
+	Applied bool
+}
+
+// Migrations возвращает список встроенных миграций драйвера, упорядоченный по версии
+func Migrations(driver string) ([]Migration, error) {
+	dir := "migrations/" + driver + "/"
+	names, err := fs.Glob(migrationFiles, dir+"*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]Migration, 0, len(names))
+	for _, name := range names {
+		base := strings.TrimSuffix(strings.TrimPrefix(name, dir), ".sql")
+		number, title, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("incorrect migration name: %s", name)
+		}
+		version, err := strconv.Atoi(number)
+		if err != nil {
+			return nil, fmt.Errorf("incorrect migration version: %s", name)
+		}
+		body, err := migrationFiles.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{Version: version, Name: title, SQL: string(body)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	if len(migrations) == 0 {
+		return nil, fmt.Errorf("no migrations for driver %s", driver)
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration %04d is missing", i+1)
+		}
+	}
+	return migrations, nil
+}
+
+// SchemaVersion возвращает сохраненную версию схемы БД:
+// для SQLite - из PRAGMA user_version, для PostgreSQL - из таблицы schema_migrations
+func (s *SQLStore) SchemaVersion() (int, error) {
+	query := `PRAGMA user_version`
+	if s.driver == DriverPostgres {
+		var exists bool
+		if err := s.queryRow(`SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+			return 0, fmt.Errorf("error getting schema version: %w", err)
+		}
+		if !exists {
+			return 0, nil
+		}
+		query = `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`
+	}
+
+	var version int
+	if err := s.queryRow(query).Scan(&version); err != nil {
+		return 0, fmt.Errorf("error getting schema version: %w", err)
+	}
+	return version, nil
+}
+
+// Migrate применяет к БД все миграции новее ее текущей версии
+// Каждая миграция выполняется в отдельной транзакции вместе с обновлением версии схемы
+func (s *SQLStore) Migrate() error {
+	migrations, err := Migrations(s.driver)
+	if err != nil {
+		return err
+	}
+	version, err := s.currentVersion()
+	if err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("%w: version %d, supported %d", ErrSchemaTooNew, version, len(migrations))
+	}
+	if err := s.stampLegacy(version); err != nil {
+		return err
+	}
+
+	for _, m := range migrations[version:] {
+		if err := s.apply(m); err != nil {
+			return fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// Status возвращает список миграций с отметкой о применении к БД
+func (s *SQLStore) Status() ([]MigrationStatus, error) {
+	migrations, err := Migrations(s.driver)
+	if err != nil {
+		return nil, err
+	}
+	version, err := s.currentVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status = append(status, MigrationStatus{Migration: m, Applied: m.Version <= version})
+	}
+	return status, nil
+}
+
+// migrationLockID - ключ блокировки PostgreSQL, под которой применяются миграции
+const migrationLockID = 75400001
+
+// apply выполняет миграцию и сохраняет новую версию схемы в одной транзакции
+// В PostgreSQL транзакция берет блокировку pg_advisory_xact_lock, поэтому экземпляры сервера,
+// запущенные одновременно, применяют миграции по очереди; миграция, которую уже применил
+// другой экземпляр, пропускается
+func (s *SQLStore) apply(m Migration) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if s.driver == DriverPostgres {
+		if _, err = tx.Exec(fmt.Sprintf(`SELECT pg_advisory_xact_lock(%d)`, migrationLockID)); err != nil {
+			return fmt.Errorf("error locking schema: %w", err)
+		}
+		var exists bool
+		if err = tx.QueryRow(`SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			var applied bool
+			query := `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version >= $1)`
+			if err = tx.QueryRow(query, m.Version).Scan(&applied); err != nil {
+				return err
+			}
+			if applied {
+				return nil
+			}
+		}
+	}
+
+	if _, err = tx.Exec(m.SQL); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(s.versionQuery(m.Version)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// versionQuery возвращает запрос, сохраняющий версию схемы БД
+func (s *SQLStore) versionQuery(version int) string {
+	if s.driver == DriverPostgres {
+		return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now());
+		INSERT INTO schema_migrations (version) VALUES (%d)`, version)
+	}
+	return fmt.Sprintf(`PRAGMA user_version = %d`, version)
+}
+
+// currentVersion возвращает версию схемы БД с учетом файлов SQLite, созданных до появления миграций
+func (s *SQLStore) currentVersion() (int, error) {
+	version, err := s.SchemaVersion()
+	if err != nil || version != 0 || s.driver != DriverSQLite {
+		return version, err
+	}
+	return s.legacyVersion()
+}
+
+// stampLegacy сохраняет в PRAGMA user_version версию, определенную для БД, созданной до появления миграций
+func (s *SQLStore) stampLegacy(version int) error {
+	stored, err := s.SchemaVersion()
+	if err != nil || stored != 0 || version == 0 {
+		return err
+	}
+	if _, err = s.exec(s.versionQuery(version)); err != nil {
+		return fmt.Errorf("error saving schema version: %w", err)
+	}
+	return nil
+}
+
+// legacyVersion определяет версию схемы БД, созданной до появления миграций
+// Такую БД создавала только исходная версия приложения: таблица scheduler без колонки user_id
+// совпадает со схемой первой миграции, остальные изменения схемы вносят нумерованные миграции
+func (s *SQLStore) legacyVersion() (int, error) {
+	var tables, columns int
+	err := s.queryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'scheduler'`).Scan(&tables)
+	if err == nil {
+		err = s.queryRow(`SELECT count(*) FROM pragma_table_info('scheduler') WHERE name = 'user_id'`).Scan(&columns)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error detecting schema version: %w", err)
+	}
+	if tables == 0 || columns > 0 {
+		return 0, nil
+	}
+	return 1, nil
+}
@@ -0,0 +1,8 @@
+-- таблица задач и индекс для поиска по времени
+CREATE TABLE IF NOT EXISTS scheduler (
+    id BIGSERIAL PRIMARY KEY,
+    date VARCHAR(8) NOT NULL DEFAULT '',
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    comment TEXT,
+    repeat VARCHAR(128) NOT NULL DEFAULT '');
+CREATE INDEX IF NOT EXISTS idx_date ON scheduler(date);
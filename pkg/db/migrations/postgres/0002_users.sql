@@ -0,0 +1,12 @@
+-- пользователи и владелец задачи, 0 соответствует общему списку задач;
+-- секретные ключи сервера, например ключ подписи JWT-токенов, если он не задан в TODO_SECRET
+ALTER TABLE scheduler ADD COLUMN user_id BIGINT NOT NULL DEFAULT 0;
+CREATE TABLE users (
+    id BIGSERIAL PRIMARY KEY,
+    login VARCHAR(64) NOT NULL UNIQUE,
+    password_hash VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now());
+CREATE TABLE server_keys (
+    name VARCHAR(64) PRIMARY KEY,
+    value TEXT NOT NULL DEFAULT '');
+CREATE INDEX idx_user_date ON scheduler(user_id, date);
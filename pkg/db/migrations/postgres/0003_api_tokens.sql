@@ -0,0 +1,11 @@
+-- персональные API-токены, сами токены не хранятся, сохраняется только их хэш
+CREATE TABLE api_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL DEFAULT 0,
+    name VARCHAR(128) NOT NULL DEFAULT '',
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    read_only BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at VARCHAR(32) NOT NULL DEFAULT '',
+    last_used_at VARCHAR(32) NOT NULL DEFAULT '',
+    expires_at VARCHAR(32) NOT NULL DEFAULT '');
+CREATE INDEX idx_tokens_user ON api_tokens(user_id);
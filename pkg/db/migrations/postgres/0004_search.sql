@@ -0,0 +1,4 @@
+-- полнотекстовый индекс по заголовку и комментарию задач,
+-- выражение индекса должно совпадать с выражением в запросах поиска
+CREATE INDEX idx_scheduler_search ON scheduler
+    USING GIN (to_tsvector('simple', title || ' ' || COALESCE(comment, '')));
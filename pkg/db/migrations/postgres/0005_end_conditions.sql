@@ -0,0 +1,3 @@
+-- условия окончания повторений: последняя допустимая дата и число оставшихся выполнений, 0 - без ограничения
+ALTER TABLE scheduler ADD COLUMN until_date VARCHAR(8) NOT NULL DEFAULT '';
+ALTER TABLE scheduler ADD COLUMN remaining INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,8 @@
+-- календарь праздников пользователя: нерабочие дни и перенесенные рабочие выходные
+CREATE TABLE holidays (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL DEFAULT 0,
+    date VARCHAR(8) NOT NULL DEFAULT '',
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    working BOOLEAN NOT NULL DEFAULT FALSE,
+    UNIQUE (user_id, date));
@@ -0,0 +1,17 @@
+-- полнотекстовый индекс FTS5 по заголовку и комментарию задач,
+-- синхронизируется с таблицей scheduler триггерами
+CREATE VIRTUAL TABLE scheduler_fts USING fts5(
+    title, comment,
+    content = 'scheduler', content_rowid = 'id',
+    tokenize = 'unicode61 remove_diacritics 2');
+CREATE TRIGGER scheduler_fts_insert AFTER INSERT ON scheduler BEGIN
+    INSERT INTO scheduler_fts (rowid, title, comment) VALUES (new.id, new.title, new.comment);
+END;
+CREATE TRIGGER scheduler_fts_delete AFTER DELETE ON scheduler BEGIN
+    INSERT INTO scheduler_fts (scheduler_fts, rowid, title, comment) VALUES ('delete', old.id, old.title, old.comment);
+END;
+CREATE TRIGGER scheduler_fts_update AFTER UPDATE OF title, comment ON scheduler BEGIN
+    INSERT INTO scheduler_fts (scheduler_fts, rowid, title, comment) VALUES ('delete', old.id, old.title, old.comment);
+    INSERT INTO scheduler_fts (rowid, title, comment) VALUES (new.id, new.title, new.comment);
+END;
+INSERT INTO scheduler_fts (scheduler_fts) VALUES ('rebuild');
@@ -0,0 +1,8 @@
+-- календарь праздников пользователя: нерабочие дни и перенесенные рабочие выходные
+CREATE TABLE holidays (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    date CHAR(8) NOT NULL DEFAULT "",
+    name VARCHAR(255) NOT NULL DEFAULT "",
+    working INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (user_id, date));
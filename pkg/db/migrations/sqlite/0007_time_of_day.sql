@@ -0,0 +1,10 @@
+-- время начала задачи в формате 15:04 и длительность в минутах, пустое время - задача на весь день
+-- часовой пояс задачи по IANA, пустая строка - часовой пояс пользователя или сервера
+ALTER TABLE scheduler ADD COLUMN start_time CHAR(5) NOT NULL DEFAULT "";
+ALTER TABLE scheduler ADD COLUMN duration INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE scheduler ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT "";
+
+-- настройки пользователя: часовой пояс по IANA, пустая строка - часовой пояс сервера
+CREATE TABLE settings (
+    user_id INTEGER PRIMARY KEY,
+    timezone VARCHAR(64) NOT NULL DEFAULT "");
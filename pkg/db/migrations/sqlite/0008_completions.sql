@@ -0,0 +1,12 @@
+-- журнал выполнения задач: заголовок и дата задачи на момент выполнения, время выполнения
+-- в формате RFC 3339 UTC и следующая дата повторяющейся задачи, пустая строка - задача завершена
+CREATE TABLE completions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    task_id INTEGER NOT NULL,
+    title VARCHAR(255) NOT NULL DEFAULT "",
+    date CHAR(8) NOT NULL DEFAULT "",
+    done_at VARCHAR(32) NOT NULL DEFAULT "",
+    next_date CHAR(8) NOT NULL DEFAULT "");
+CREATE INDEX idx_completions_task ON completions(user_id, task_id);
+CREATE INDEX idx_completions_done ON completions(user_id, done_at);
@@ -0,0 +1,11 @@
+-- отметки для отмены удаления и выполнения задач: состояние задачи до операции в JSON,
+-- отметка о выполнении, которая удаляется при отмене, и срок действия токена в формате RFC 3339 UTC
+CREATE TABLE undo (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    task_id INTEGER NOT NULL,
+    task TEXT NOT NULL DEFAULT "",
+    completion_id INTEGER NOT NULL DEFAULT 0,
+    expires_at VARCHAR(32) NOT NULL DEFAULT "");
+CREATE INDEX idx_undo_expires ON undo(expires_at);
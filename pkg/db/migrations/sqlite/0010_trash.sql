@@ -0,0 +1,3 @@
+-- время удаления задачи в корзину в формате RFC 3339 UTC, пустая строка - задача не удалена
+ALTER TABLE scheduler ADD COLUMN deleted_at VARCHAR(32) NOT NULL DEFAULT "";
+CREATE INDEX idx_scheduler_deleted ON scheduler(deleted_at);
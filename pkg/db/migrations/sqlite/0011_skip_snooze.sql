@@ -0,0 +1,6 @@
+-- вид отметки в журнале: done - выполнение, skipped - пропуск повторения, snoozed - перенос повторения
+ALTER TABLE completions ADD COLUMN kind VARCHAR(16) NOT NULL DEFAULT "done";
+
+-- исходная дата перенесенного повторения, от которой считаются следующие даты серии,
+-- пустая строка - повторение не переносилось
+ALTER TABLE scheduler ADD COLUMN snoozed_from CHAR(8) NOT NULL DEFAULT "";
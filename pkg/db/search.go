@@ -0,0 +1,145 @@
+package db
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// маркеры начала и конца найденного фрагмента в сниппете,
+// после экранирования текста заменяются на теги <mark>
+const (
+	markStart = "\x02"
+	markEnd   = "\x03"
+)
+
+// searchTerm - условие полнотекстового поиска: слово или фраза из нескольких слов
+// Последнее слово условия ищется по префиксу, если условие не заключено в кавычки
+type searchTerm struct {
+	tokens []string
+	prefix bool
+}
+
+// parseSearch разбирает строку поиска на условия
+// Текст в двойных кавычках ищется как точная фраза, остальные слова - по префиксу
+// Слова разбиваются так же, как в индексе: по буквам и цифрам с приведением к нижнему регистру
+func parseSearch(search string) []searchTerm {
+	var terms []searchTerm
+	for i, part := range strings.Split(search, `"`) {
+		phrase := i%2 == 1
+		if phrase {
+			if tokens := tokenize(part); len(tokens) > 0 {
+				terms = append(terms, searchTerm{tokens: tokens})
+			}
+			continue
+		}
+		for _, word := range strings.Fields(part) {
+			if tokens := tokenize(word); len(tokens) > 0 {
+				terms = append(terms, searchTerm{tokens: tokens, prefix: true})
+			}
+		}
+	}
+	return terms
+}
+
+// tokenize разбивает текст на слова из букв и цифр в нижнем регистре
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// ftsQuery преобразует условия поиска в запрос FTS5: "слово"* "точная фраза"
+func ftsQuery(terms []searchTerm) string {
+	parts := make([]string, 0, len(terms))
+	for _, term := range terms {
+		part := `"` + strings.Join(term.tokens, " ") + `"`
+		if term.prefix {
+			part += "*"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+// tsQuery преобразует условия поиска в запрос to_tsquery PostgreSQL: слово:* & точная <-> фраза
+func tsQuery(terms []searchTerm) string {
+	parts := make([]string, 0, len(terms))
+	for _, term := range terms {
+		tokens := append([]string(nil), term.tokens...)
+		if term.prefix {
+			tokens[len(tokens)-1] += ":*"
+		}
+		parts = append(parts, "("+strings.Join(tokens, " <-> ")+")")
+	}
+	return strings.Join(parts, " & ")
+}
+
+// highlight экранирует сниппет для вывода в HTML и заменяет маркеры найденных фрагментов тегами <mark>
+func highlight(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	return strings.NewReplacer(markStart, "<mark>", markEnd, "</mark>").Replace(escaped)
+}
+
+// matchText проверяет текст на соответствие условиям поиска
+// Возвращает отметки найденных условий и текст с отмеченными найденными словами
+func matchText(text string, terms []searchTerm) ([]bool, string) {
+	type word struct {
+		start, end int
+		token      string
+	}
+	var words []word
+	start := -1
+	for i, r := range text + " " {
+		letter := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if letter && start < 0 {
+			start = i
+		}
+		if !letter && start >= 0 {
+			words = append(words, word{start: start, end: i, token: strings.ToLower(text[start:i])})
+			start = -1
+		}
+	}
+
+	marked := make([]bool, len(words))
+	found := make([]bool, len(terms))
+	matchedAny := false
+	for n, term := range terms {
+		matched := false
+		for i := 0; i+len(term.tokens) <= len(words); i++ {
+			ok := true
+			for j, token := range term.tokens {
+				last := j == len(term.tokens)-1
+				w := words[i+j].token
+				if w != token && !(last && term.prefix && strings.HasPrefix(w, token)) {
+					ok = false
+					break
+				}
+			}
+			if ok {
+				matched = true
+				for j := range term.tokens {
+					marked[i+j] = true
+				}
+			}
+		}
+		found[n] = matched
+		matchedAny = matchedAny || matched
+	}
+	if !matchedAny {
+		return found, ""
+	}
+
+	var b strings.Builder
+	pos := 0
+	for i, w := range words {
+		if !marked[i] {
+			continue
+		}
+		b.WriteString(text[pos:w.start])
+		b.WriteString(markStart + text[w.start:w.end] + markEnd)
+		pos = w.end
+	}
+	b.WriteString(text[pos:])
+	return found, b.String()
+}
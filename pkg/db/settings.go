@@ -0,0 +1,26 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Timezone получает часовой пояс пользователя, пустая строка - часовой пояс сервера
+func (s *SQLStore) Timezone(userID int64) (string, error) {
+	var tz string
+	err := s.queryRow(`SELECT timezone FROM settings WHERE user_id = ?`, userID).Scan(&tz)
+	if err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("error getting settings: %w", err)
+	}
+	return tz, nil
+}
+
+// SetTimezone сохраняет часовой пояс пользователя
+func (s *SQLStore) SetTimezone(userID int64, tz string) error {
+	query := `INSERT INTO settings (user_id, timezone) VALUES (?, ?)
+			ON CONFLICT (user_id) DO UPDATE SET timezone = excluded.timezone`
+	if _, err := s.exec(query, userID, tz); err != nil {
+		return fmt.Errorf("error saving settings: %w", err)
+	}
+	return nil
+}
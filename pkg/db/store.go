@@ -0,0 +1,141 @@
+package db
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ошибки хранилища, общие для всех реализаций
+var (
+	ErrTaskNotFound  = errors.New("task not found")
+	ErrUserNotFound  = errors.New("user not found")
+	ErrTokenNotFound = errors.New("token not found")
+
+	ErrHolidayNotFound = errors.New("holiday not found")
+	ErrUndoNotFound    = errors.New("undo token not found or expired")
+)
+
+// TaskStore - хранилище задач, все операции выполняются в пределах задач одного пользователя
+type TaskStore interface {
+	// Add добавляет новую задачу, возвращает ее id
+	Add(userID int64, task *Task) (int64, error)
+	// Get получает задачу по id
+	Get(userID int64, id string) (*Task, error)
+	// List получает страницу задач, упорядоченных по дате
+	List(userID int64, page Page) (*TaskPage, error)
+	// Search получает страницу задач, найденных по дате в формате 02.01.2006 или тексту,
+	// дополнительно ограниченных условием filter, если оно задано
+	Search(userID int64, page Page, search string, filter *Filter) (*TaskPage, error)
+	// Find получает страницу задач, подходящих под условие структурированного поиска, упорядоченных по дате
+	Find(userID int64, page Page, filter *Filter) (*TaskPage, error)
+	// Update обновляет существующую задачу
+	Update(userID int64, task *Task) error
+	// Delete перемещает задачу в корзину по id, если undo задан - с возможностью отменить удаление по токену
+	Delete(userID int64, id string, undo *Undo) error
+	// Undo отменяет удаление или выполнение задачи по токену, действующему на момент now,
+	// возвращает восстановленную задачу
+	Undo(userID int64, token string, now time.Time) (*Task, error)
+	// UpdateDate переносит задачу на новую дату
+	UpdateDate(userID int64, nextDate string, id string) error
+}
+
+// TrashStore - корзина удаленных задач; задачи в корзине не возвращаются остальными методами TaskStore
+type TrashStore interface {
+	// Trash получает задачи из корзины, сначала удаленные последними
+	Trash(userID int64) ([]*Task, error)
+	// RestoreTask возвращает задачу из корзины в список задач
+	RestoreTask(userID int64, id string) error
+	// EmptyTrash окончательно удаляет все задачи пользователя из корзины, возвращает их количество
+	EmptyTrash(userID int64) (int64, error)
+	// PurgeTrash окончательно удаляет задачи всех пользователей, попавшие в корзину раньше before
+	PurgeTrash(before time.Time) (int64, error)
+}
+
+// UserStore - хранилище учетных записей пользователей
+type UserStore interface {
+	// AddUser добавляет пользователя, возвращает его id
+	AddUser(login, passwordHash string) (int64, error)
+	// GetUser получает пользователя по id
+	GetUser(id int64) (*User, error)
+	// GetUserByLogin получает пользователя по логину
+	GetUserByLogin(login string) (*User, error)
+}
+
+// TokenStore - хранилище персональных API-токенов
+type TokenStore interface {
+	// AddToken сохраняет токен по хэшу его значения, возвращает id токена
+	AddToken(userID int64, token *APIToken, hash string) (int64, error)
+	// Tokens получает список токенов пользователя
+	Tokens(userID int64) ([]*APIToken, error)
+	// TokenByHash получает токен по хэшу его значения
+	TokenByHash(hash string) (*APIToken, error)
+	// RenameToken изменяет название токена
+	RenameToken(userID int64, id string, name string) error
+	// TouchToken сохраняет время последнего использования токена
+	TouchToken(id string, usedAt time.Time) error
+	// DeleteToken отзывает токен
+	DeleteToken(userID int64, id string) error
+}
+
+// KeyStore - хранилище секретных ключей сервера
+type KeyStore interface {
+	// ServerKey получает ключ name, а если его еще нет - сохраняет и возвращает значение, созданное generate
+	ServerKey(name string, generate func() (string, error)) (string, error)
+}
+
+// HolidayStore - хранилище календаря праздников и перенесенных рабочих дней
+type HolidayStore interface {
+	// SetHolidays добавляет дни в календарь, существующие дни с теми же датами заменяются
+	SetHolidays(userID int64, holidays []*Holiday) error
+	// Holidays получает дни календаря в периоде from - to включительно, пустая граница не ограничивает период
+	Holidays(userID int64, from, to string) ([]*Holiday, error)
+	// DeleteHoliday удаляет день из календаря
+	DeleteHoliday(userID int64, date string) error
+}
+
+// SettingsStore - хранилище настроек пользователя
+type SettingsStore interface {
+	// Timezone получает часовой пояс пользователя по IANA, пустая строка - часовой пояс не задан
+	Timezone(userID int64) (string, error)
+	// SetTimezone сохраняет часовой пояс пользователя, пустая строка сбрасывает его
+	SetTimezone(userID int64, tz string) error
+}
+
+// CompletionStore - журнал выполнения, пропуска и переноса задач
+type CompletionStore interface {
+	// Complete записывает отметку журнала и в той же транзакции удаляет задачу completion.TaskID,
+	// если next равно nil, или сохраняет задачу next, перенесенную на новую дату;
+	// если undo задан, операцию можно отменить по токену
+	Complete(userID int64, completion *Completion, next *Task, undo *Undo) error
+	// TaskHistory получает отметки журнала по задаче в порядке их записи
+	TaskHistory(userID int64, taskID string) ([]*Completion, error)
+	// Completions получает отметки журнала с from включительно до to в порядке их записи
+	Completions(userID int64, from, to time.Time) ([]*Completion, error)
+}
+
+// Store - полный набор хранилищ, необходимых серверу API
+type Store interface {
+	TaskStore
+	TrashStore
+	UserStore
+	TokenStore
+	KeyStore
+	HolidayStore
+	SettingsStore
+	CompletionStore
+	Close() error
+}
+
+// parseID преобразует строковый id в число, некорректный id не соответствует ни одной записи
+func parseID(id string) (int64, bool) {
+	n, err := strconv.ParseInt(strings.TrimSpace(id), 10, 64)
+	return n, err == nil
+}
+
+// проверка соответствия реализаций интерфейсу хранилища
+var (
+	_ Store = (*SQLStore)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
@@ -6,22 +6,65 @@ import (
 	"fmt"
 	"time"
 )
+
 const DateFormat = "20060102"
 
+// SearchRankScale - множитель, с которым релевантность поиска округляется до целого ранга задачи
+const SearchRankScale = 1000000
 
 // Task - структура задачи в системе, соответствует записям в таблице БД
 type Task struct {
-    ID      string `json:"id,omitempty"`
-    Date    string `json:"date"`
-	Title	string `json:"title"`
-	Comment	string `json:"comment"`
-	Repeat	string `json:"repeat"`	
+	ID      string `json:"id,omitempty"`
+	Date    string `json:"date"`
+	Title   string `json:"title"`
+	Comment string `json:"comment"`
+	Repeat  string `json:"repeat"`
+	// Until - последняя дата повторений включительно, пустая строка - без ограничения
+	Until string `json:"until,omitempty"`
+	// Remaining - сколько раз еще нужно выполнить задачу, включая текущую дату, 0 - без ограничения
+	Remaining int `json:"remaining,omitempty"`
+	// Time - время начала в формате 15:04, пустая строка - задача на весь день
+	Time string `json:"time,omitempty"`
+	// Duration - длительность в минутах, задается только вместе со временем начала
+	Duration int `json:"duration,omitempty"`
+	// Timezone - часовой пояс задачи по IANA, пустая строка - часовой пояс пользователя
+	Timezone string `json:"timezone,omitempty"`
+	// SnoozedFrom - исходная дата перенесенного повторения, от нее считаются следующие даты серии,
+	// пустая строка - повторение не переносилось
+	SnoozedFrom string `json:"snoozed_from,omitempty"`
+	// Snippet - фрагмент текста с найденными словами в тегах <mark>, заполняется только при поиске
+	Snippet string `json:"snippet,omitempty"`
+	// RepeatText - описание правила повторения на языке запроса, в базе не хранится
+	RepeatText string `json:"repeat_text,omitempty"`
+	// DeletedAt - время удаления в корзину в формате TimestampFormat, заполняется только для задач из корзины
+	DeletedAt string `json:"deleted_at,omitempty"`
+}
+
+// Cursor - позиция последней выданной задачи для постраничного вывода
+// Задачи упорядочены по убыванию Rank (релевантности при поиске по тексту), затем по дате и id
+type Cursor struct {
+	Rank int
+	Date string
+	ID   int64
+}
+
+// Page - параметры запроса страницы задач
+type Page struct {
+	Limit int
+	After *Cursor // nil для первой страницы
+}
+
+// TaskPage - страница списка задач
+type TaskPage struct {
+	Tasks []*Task
+	Next  *Cursor // nil на последней странице
+	Total int     // количество всех задач, подходящих под запрос
 }
 
 // Response - структура для формирования ответов сервера
 type Response struct {
-	ID		string `json:"id,omitempty"`
-	Error	string `json:"error,omitempty"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
 }
 
 // MarshalJSON — метод сериализации структуры Response в JSON
@@ -32,148 +75,225 @@ func (r Response) MarshalJSON() ([]byte, error) {
 	return json.Marshal(map[string]string{"id": r.ID})
 }
 
-// AddTask добавляет новую задачу в БД, возвращает id задачи и ошибку в случае некорректной обработки запроса
-func AddTask(task *Task) (int64, error) {
-	query := `INSERT into scheduler (date, title, comment, repeat)
-			VALUES (?, ?, ?, ?)`
+// Add добавляет новую задачу пользователя в БД, возвращает id задачи и ошибку в случае некорректной обработки запроса
+func (s *SQLStore) Add(userID int64, task *Task) (int64, error) {
+	query := `INSERT into scheduler (date, title, comment, repeat, until_date, remaining,
+				start_time, duration, timezone, user_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
-	res, err := DB.Exec(query, task.Date, task.Title, task.Comment, task.Repeat)
-		if err != nil {
-			return 0, fmt.Errorf("SQL query error: %w", err)
-		}
-		return res.LastInsertId()
+	id, err := s.insert(query, task.Date, task.Title, task.Comment, task.Repeat, task.Until, task.Remaining,
+		task.Time, task.Duration, task.Timezone, userID)
+	if err != nil {
+		return 0, fmt.Errorf("SQL query error: %w", err)
+	}
+	return id, nil
 }
 
-// Tasks получает список всех задач из БД 
-func Tasks(limit int) ([]*Task, error) {
-	query := `SELECT id, date, title, comment, repeat
-			FROM scheduler ORDER BY date ASC LIMIT ?`
+// List получает страницу задач пользователя из БД, упорядоченных по дате
+func (s *SQLStore) List(userID int64, page Page) (*TaskPage, error) {
+	return s.Find(userID, page, nil)
+}
 
-	rows, err := DB.Query(query, limit)
-	if err != nil {
-		return nil, fmt.Errorf("SQL query error: %w", err)
+// Search получает страницу задач пользователя с возможностью поиска по дате или тексту,
+// дополнительно ограниченных условием filter, если оно задано
+// Текст ищется по словам с учетом регистра любых алфавитов: слова - по префиксу, фразы в кавычках - целиком
+// Задачи упорядочены по релевантности, совпадения в заголовке весят больше совпадений в комментарии;
+// релевантность округляется до целого (SearchRankScale долей), чтобы ее можно было сохранить в курсоре
+// Статистика полнотекстового индекса зависит от всех задач, поэтому при их добавлении релевантность
+// и положение задач на следующих страницах могут измениться
+func (s *SQLStore) Search(userID int64, page Page, search string, filter *Filter) (*TaskPage, error) {
+	if searchDate, err := time.Parse("02.01.2006", search); err == nil {
+		return s.Find(userID, page, And(&Filter{Field: FieldOn, Value: searchDate.Format(DateFormat)}, filter))
 	}
-	defer rows.Close()
 
-	return scanResult (rows)
+	terms := parseSearch(search)
+	if len(terms) == 0 {
+		return &TaskPage{}, nil
+	}
+
+	// полнотекстовый поиск, сниппет строится по найденным словам; bm25 тем меньше, чем выше релевантность
+	query := `SELECT s.id, s.date, s.title, s.comment, s.repeat, s.until_date, s.remaining,
+				s.start_time, s.duration, s.timezone, s.snoozed_from,
+				snippet(scheduler_fts, -1, char(2), char(3), '…', 12) AS snippet,
+				CAST(ROUND(-bm25(scheduler_fts, 2.0, 1.0) * ?) AS INTEGER) AS rank
+			FROM scheduler_fts JOIN scheduler s ON s.id = scheduler_fts.rowid
+			WHERE scheduler_fts MATCH ? AND s.user_id = ? AND s.deleted_at = ''`
+	args := []any{SearchRankScale, ftsQuery(terms), userID}
+	if s.driver == DriverPostgres {
+		query = `SELECT id, date, title, comment, repeat, until_date, remaining, start_time, duration, timezone,
+				snoozed_from, ts_headline('simple', title || ' ' || COALESCE(comment, ''), q,
+					'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', MaxWords=12, MinWords=4') AS snippet,
+				ROUND(ts_rank(setweight(to_tsvector('simple', title), 'A') ||
+					setweight(to_tsvector('simple', COALESCE(comment, '')), 'B'), q) * ?)::bigint AS rank
+			FROM scheduler, to_tsquery('simple', ?) q
+			WHERE to_tsvector('simple', title || ' ' || COALESCE(comment, '')) @@ q AND user_id = ? AND deleted_at = ''`
+		args = []any{SearchRankScale, tsQuery(terms), userID}
+	}
+
+	result, err := s.page(query, args, filter, page)
+	if err != nil {
+		return nil, fmt.Errorf("task search error: %w", err)
+	}
+	for _, task := range result.Tasks {
+		task.Snippet = highlight(task.Snippet)
+	}
+	return result, nil
 }
 
-// TasksWithSearch получает список задач с возможностью поиска по дате или тексту
-func TasksWithSearch(limit int, search string) ([]*Task, error) {
-	if searchDate, err := time.Parse("02.01.2006", search); err == nil {
-		formattedDate := searchDate.Format(DateFormat)
-		query := `SELECT id, date, title, comment, repeat
-			FROM scheduler WHERE date = ? 
-			ORDER BY date ASC LIMIT ?`
+// Find получает страницу задач пользователя, подходящих под условие структурированного поиска,
+// упорядоченных по дате; nil-условие соответствует всем задачам, кроме задач в корзине
+func (s *SQLStore) Find(userID int64, page Page, filter *Filter) (*TaskPage, error) {
+	query := `SELECT id, date, title, comment, repeat, until_date, remaining, start_time, duration, timezone,
+				snoozed_from, '' AS snippet, 0 AS rank
+			FROM scheduler WHERE user_id = ? AND deleted_at = ''`
+	result, err := s.page(query, []any{userID}, filter, page)
+	if err != nil {
+		return nil, fmt.Errorf("SQL query error: %w", err)
+	}
+	return result, nil
+}
 
-		rows, err :=DB.Query(query, formattedDate, limit)
+// page выполняет запрос страницы задач, подходящих под условие filter, и подсчет их общего количества
+// Запрос должен возвращать колонки id, date, title, comment, repeat, until_date, remaining,
+// start_time, duration, timezone, snoozed_from, snippet и rank
+func (s *SQLStore) page(query string, args []any, filter *Filter, page Page) (*TaskPage, error) {
+	query = `SELECT id, date, title, comment, repeat, until_date, remaining, start_time, duration, timezone,
+		snoozed_from, snippet, rank FROM (` + query + `) t`
+	if filter != nil {
+		where, filterArgs, err := s.filterSQL(filter)
 		if err != nil {
-		return nil, fmt.Errorf("error searching by date: %w", err)
+			return nil, err
 		}
-		defer rows.Close()
-
-		return scanResult(rows)
+		query += " WHERE " + where
+		args = append(args, filterArgs...)
 	}
-	searchValue := "%" + search + "%"
 
-	query := `SELECT id, date, title, comment, repeat
-			FROM scheduler WHERE LOWER (title) LIKE ? OR LOWER (comment) LIKE ?
-			ORDER BY date ASC LIMIT ?`
+	result := &TaskPage{}
+	if err := s.queryRow(`SELECT COUNT(*) FROM (`+query+`) c`, args...).Scan(&result.Total); err != nil {
+		return nil, err
+	}
 
-	rows, err := DB.Query(query, searchValue, searchValue, limit)
+	if page.After != nil {
+		query = `SELECT * FROM (` + query + `) p WHERE rank < ? OR rank = ? AND (date > ? OR date = ? AND id > ?)`
+		after := page.After
+		args = append(args, after.Rank, after.Rank, after.Date, after.Date, after.ID)
+	}
+	rows, err := s.query(query+` ORDER BY rank DESC, date ASC, id ASC LIMIT ?`, append(args, page.Limit+1)...)
 	if err != nil {
-		return nil, fmt.Errorf("task search error: %w", err)
+		return nil, err
 	}
 	defer rows.Close()
-	
-	return scanResult(rows)
-}
-
-// scanResult позволяет сканировать результаты запроса в срезе задач
-func scanResult(rows *sql.Rows) ([]*Task, error) {
-	var tasks []*Task
 
+	var ranks []int
 	for rows.Next() {
 		task := &Task{}
-		err := rows.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat)
+		var rank int
+		err := rows.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat,
+			&task.Until, &task.Remaining, &task.Time, &task.Duration, &task.Timezone, &task.SnoozedFrom,
+			&task.Snippet, &rank)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning row: %w", err)
 		}
-	tasks = append(tasks, task)
+		result.Tasks = append(result.Tasks, task)
+		ranks = append(ranks, rank)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error processing result: %w", err)
 	}
-	return tasks, nil
+
+	if page.Limit > 0 && len(result.Tasks) > page.Limit {
+		result.Tasks = result.Tasks[:page.Limit]
+		last := result.Tasks[page.Limit-1]
+		id, _ := parseID(last.ID)
+		result.Next = &Cursor{Rank: ranks[page.Limit-1], Date: last.Date, ID: id}
+	}
+	return result, nil
 }
 
-// GetTasks получает задачу по ее id
-func GetTask(id string) (*Task, error) {
-	task := &Task{}
-	query := `SELECT id, date, title, comment, repeat
+// Get получает задачу пользователя по ее id, задачи в корзине не находятся
+func (s *SQLStore) Get(userID int64, id string) (*Task, error) {
+	if _, ok := parseID(id); !ok {
+		return nil, ErrTaskNotFound
+	}
+	return scanTask(s.queryRow(selectTaskQuery, id, userID))
+}
+
+// selectTaskQuery - запрос задачи пользователя по id без задач в корзине, строку результата разбирает scanTask
+const selectTaskQuery = `SELECT id, date, title, comment, repeat, until_date, remaining, start_time, duration, timezone,
+				snoozed_from
 			FROM scheduler
-			WHERE id = ?`
-	err := DB.QueryRow(query, id).Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat)
+			WHERE id = ? AND user_id = ? AND deleted_at = ''`
 
+// scanTask сканирует результат запроса selectTaskQuery в структуру задачи
+func scanTask(row *sql.Row) (*Task, error) {
+	task := &Task{}
+	err := row.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat,
+		&task.Until, &task.Remaining, &task.Time, &task.Duration, &task.Timezone, &task.SnoozedFrom)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("task not found")
-		}	
+			return nil, ErrTaskNotFound
+		}
 		return nil, fmt.Errorf("error getting task: %w", err)
 	}
 	return task, nil
 }
-// UpdateTask обновляет существующую задачу в БД
-func UpdateTask(task *Task) error {
-	query := `UPDATE scheduler
-			SET date = ?, title = ?, comment = ?, repeat = ?
-			WHERE ID = ?`
 
-	res, err := DB.Exec(query, task.Date, task.Title, task.Comment, task.Repeat, task.ID)
-		if err != nil {
-			return fmt.Errorf("error updating task: %w", err)
-		}
-	count, err := res.RowsAffected()
-		if err != nil {
-			return fmt.Errorf("error getting affected rows: %w", err)
-		}
-	if count == 0 {
-		return fmt.Errorf("incorrect id for updating task")
+// updateTaskQuery - запрос на обновление всех полей задачи не из корзины, аргументы формирует updateTaskArgs
+const updateTaskQuery = `UPDATE scheduler
+			SET date = ?, title = ?, comment = ?, repeat = ?, until_date = ?, remaining = ?,
+				start_time = ?, duration = ?, timezone = ?, snoozed_from = ?
+			WHERE ID = ? AND user_id = ? AND deleted_at = ''`
+
+// updateTaskArgs возвращает аргументы запроса updateTaskQuery
+func updateTaskArgs(userID int64, task *Task) []any {
+	return []any{task.Date, task.Title, task.Comment, task.Repeat, task.Until, task.Remaining,
+		task.Time, task.Duration, task.Timezone, task.SnoozedFrom, task.ID, userID}
+}
+
+// Update обновляет существующую задачу пользователя в БД
+func (s *SQLStore) Update(userID int64, task *Task) error {
+	if _, ok := parseID(task.ID); !ok {
+		return ErrTaskNotFound
 	}
-	return nil
+	return s.execAffected(ErrTaskNotFound, "error updating task", updateTaskQuery, updateTaskArgs(userID, task)...)
 }
 
-// DeleteTask удаляет существующую задачу по ее идентификатору
-func DeleteTask(id string) error {
-	query := `DELETE FROM scheduler WHERE id = ?`
-	res, err := DB.Exec(query, id)
-		if err != nil {
-			return fmt.Errorf("error deleting task: %w", err)
-		}
+// Delete перемещает существующую задачу пользователя в корзину по ее идентификатору
+// Если undo задан, задача в той же транзакции сохраняется для отмены удаления по токену
+func (s *SQLStore) Delete(userID int64, id string, undo *Undo) error {
+	if _, ok := parseID(id); !ok {
+		return ErrTaskNotFound
+	}
+	query := `UPDATE scheduler SET deleted_at = ? WHERE id = ? AND user_id = ? AND deleted_at = ''`
+	deletedAt := time.Now().UTC().Format(TimestampFormat)
+	if undo == nil {
+		return s.execAffected(ErrTaskNotFound, "error deleting task", query, deletedAt, id, userID)
+	}
 
-	count, err := res.RowsAffected()
-		if err != nil {
-			return fmt.Errorf("error getting affected rows: %w", err)
-		}
-	if count == 0 {
-		return fmt.Errorf("task not found")
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
 	}
-	return nil
-}
+	defer tx.Rollback()
 
-// UpdateDate обновляет дату повторяющихся задач
-func UpdateDate(nextDate string, id string) error {
-	query := `UPDATE scheduler SET date = ? WHERE id = ?`
-	res, err := DB.Exec(query, nextDate, id)
+	task, err := scanTask(tx.QueryRow(s.rebind(selectTaskQuery), id, userID))
 	if err != nil {
-		return fmt.Errorf("error updating task date: %w", err)
+		return err
 	}
-	count, err := res.RowsAffected()
-		if err != nil {	
-			return fmt.Errorf("error getting affected rows: %w", err)
-		}
-	if count == 0 {
-		return fmt.Errorf("task not found")
+	if err := s.txExecAffected(tx, ErrTaskNotFound, "error deleting task", query, deletedAt, id, userID); err != nil {
+		return err
+	}
+	if err := s.saveUndo(tx, userID, task, undo, 0); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateDate обновляет дату повторяющихся задач пользователя
+func (s *SQLStore) UpdateDate(userID int64, nextDate string, id string) error {
+	if _, ok := parseID(id); !ok {
+		return ErrTaskNotFound
 	}
-	return nil
+	query := `UPDATE scheduler SET date = ? WHERE id = ? AND user_id = ? AND deleted_at = ''`
+	return s.execAffected(ErrTaskNotFound, "error updating task date", query, nextDate, id, userID)
 }
\ No newline at end of file
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// APIToken - персональный API-токен пользователя, соответствует записям в таблице api_tokens
+// Время хранится в формате RFC 3339, пустая строка означает отсутствие значения
+type APIToken struct {
+	ID         string `json:"id"`
+	UserID     int64  `json:"-"`
+	Name       string `json:"name"`
+	ReadOnly   bool   `json:"read_only"`
+	CreatedAt  string `json:"created_at"`
+	LastUsedAt string `json:"last_used_at"`
+	ExpiresAt  string `json:"expires_at"`
+}
+
+// Expired сообщает, истек ли срок действия токена к моменту now
+func (t *APIToken) Expired(now time.Time) bool {
+	if t.ExpiresAt == "" {
+		return false
+	}
+	expires, err := time.Parse(time.RFC3339, t.ExpiresAt)
+	return err != nil || !now.Before(expires)
+}
+
+// AddToken сохраняет новый API-токен пользователя по хэшу его значения, возвращает id токена
+func (s *SQLStore) AddToken(userID int64, token *APIToken, hash string) (int64, error) {
+	query := `INSERT INTO api_tokens (user_id, name, token_hash, read_only, created_at, expires_at)
+			VALUES (?, ?, ?, ?, ?, ?)`
+
+	id, err := s.insert(query, userID, token.Name, hash, token.ReadOnly, token.CreatedAt, token.ExpiresAt)
+	if err != nil {
+		return 0, fmt.Errorf("error adding token: %w", err)
+	}
+	return id, nil
+}
+
+// Tokens получает список API-токенов пользователя
+func (s *SQLStore) Tokens(userID int64) ([]*APIToken, error) {
+	query := `SELECT id, user_id, name, read_only, created_at, last_used_at, expires_at
+			FROM api_tokens WHERE user_id = ? ORDER BY id ASC`
+
+	rows, err := s.query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("SQL query error: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []*APIToken
+	for rows.Next() {
+		token := &APIToken{}
+		if err := rows.Scan(&token.ID, &token.UserID, &token.Name, &token.ReadOnly,
+			&token.CreatedAt, &token.LastUsedAt, &token.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error processing result: %w", err)
+	}
+	return tokens, nil
+}
+
+// TokenByHash получает API-токен по хэшу его значения
+func (s *SQLStore) TokenByHash(hash string) (*APIToken, error) {
+	token := &APIToken{}
+	query := `SELECT id, user_id, name, read_only, created_at, last_used_at, expires_at
+			FROM api_tokens WHERE token_hash = ?`
+	err := s.queryRow(query, hash).Scan(&token.ID, &token.UserID, &token.Name, &token.ReadOnly,
+		&token.CreatedAt, &token.LastUsedAt, &token.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTokenNotFound
+		}
+		return nil, fmt.Errorf("error getting token: %w", err)
+	}
+	return token, nil
+}
+
+// RenameToken изменяет название API-токена пользователя
+func (s *SQLStore) RenameToken(userID int64, id string, name string) error {
+	if _, ok := parseID(id); !ok {
+		return ErrTokenNotFound
+	}
+	query := `UPDATE api_tokens SET name = ? WHERE id = ? AND user_id = ?`
+	return s.execAffected(ErrTokenNotFound, "error renaming token", query, name, id, userID)
+}
+
+// TouchToken сохраняет время последнего использования API-токена
+func (s *SQLStore) TouchToken(id string, usedAt time.Time) error {
+	if _, ok := parseID(id); !ok {
+		return ErrTokenNotFound
+	}
+	query := `UPDATE api_tokens SET last_used_at = ? WHERE id = ?`
+	return s.execAffected(ErrTokenNotFound, "error updating token", query, usedAt.UTC().Format(time.RFC3339), id)
+}
+
+// DeleteToken отзывает API-токен пользователя
+func (s *SQLStore) DeleteToken(userID int64, id string) error {
+	if _, ok := parseID(id); !ok {
+		return ErrTokenNotFound
+	}
+	query := `DELETE FROM api_tokens WHERE id = ? AND user_id = ?`
+	return s.execAffected(ErrTokenNotFound, "error deleting token", query, id, userID)
+}
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// Trash получает задачи пользователя из корзины, сначала удаленные последними
+func (s *SQLStore) Trash(userID int64) ([]*Task, error) {
+	query := `SELECT id, date, title, comment, repeat, until_date, remaining, start_time, duration, timezone,
+				snoozed_from, deleted_at
+			FROM scheduler
+			WHERE user_id = ? AND deleted_at <> ''
+			ORDER BY deleted_at DESC, id DESC`
+	rows, err := s.query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("SQL query error: %w", err)
+	}
+	defer rows.Close()
+
+	var tasks []*Task
+	for rows.Next() {
+		task := &Task{}
+		err := rows.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat,
+			&task.Until, &task.Remaining, &task.Time, &task.Duration, &task.Timezone,
+			&task.SnoozedFrom, &task.DeletedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error processing result: %w", err)
+	}
+	return tasks, nil
+}
+
+// RestoreTask возвращает задачу пользователя из корзины в список задач
+func (s *SQLStore) RestoreTask(userID int64, id string) error {
+	if _, ok := parseID(id); !ok {
+		return ErrTaskNotFound
+	}
+	query := `UPDATE scheduler SET deleted_at = '' WHERE id = ? AND user_id = ? AND deleted_at <> ''`
+	return s.execAffected(ErrTaskNotFound, "error restoring task", query, id, userID)
+}
+
+// EmptyTrash окончательно удаляет все задачи пользователя из корзины, возвращает их количество
+func (s *SQLStore) EmptyTrash(userID int64) (int64, error) {
+	res, err := s.exec(`DELETE FROM scheduler WHERE user_id = ? AND deleted_at <> ''`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("error emptying trash: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// PurgeTrash окончательно удаляет задачи всех пользователей, попавшие в корзину раньше before,
+// возвращает их количество
+func (s *SQLStore) PurgeTrash(before time.Time) (int64, error) {
+	query := `DELETE FROM scheduler WHERE deleted_at <> '' AND deleted_at < ?`
+	res, err := s.exec(query, before.UTC().Format(TimestampFormat))
+	if err != nil {
+		return 0, fmt.Errorf("error purging trash: %w", err)
+	}
+	return res.RowsAffected()
+}
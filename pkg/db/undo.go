@@ -0,0 +1,97 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Undo - отметка для отмены удаления или выполнения задачи: до ExpiresAt (в формате TimestampFormat)
+// по токену Token задачу можно вернуть в состояние, в котором она была до операции
+type Undo struct {
+	Token     string
+	ExpiresAt string
+}
+
+// insertTaskWithIDQuery - запрос на восстановление задачи с ее прежним id
+const insertTaskWithIDQuery = `INSERT INTO scheduler (id, date, title, comment, repeat, until_date, remaining,
+				start_time, duration, timezone, snoozed_from, user_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+// restoreTaskQuery - запрос на восстановление всех полей задачи, в том числе из корзины,
+// аргументы формирует updateTaskArgs
+const restoreTaskQuery = `UPDATE scheduler
+			SET date = ?, title = ?, comment = ?, repeat = ?, until_date = ?, remaining = ?,
+				start_time = ?, duration = ?, timezone = ?, snoozed_from = ?, deleted_at = ''
+			WHERE ID = ? AND user_id = ?`
+
+// saveUndo сохраняет в транзакции tx состояние задачи task до операции для отмены по токену undo
+// completionID - отметка о выполнении, которая удаляется при отмене, 0 - операция без отметки
+// Заодно удаляются просроченные отметки отмены всех пользователей
+func (s *SQLStore) saveUndo(tx *sql.Tx, userID int64, task *Task, undo *Undo, completionID int64) error {
+	snapshot, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("error saving undo: %w", err)
+	}
+	now := time.Now().UTC().Format(TimestampFormat)
+	if _, err := tx.Exec(s.rebind(`DELETE FROM undo WHERE expires_at <= ?`), now); err != nil {
+		return fmt.Errorf("error saving undo: %w", err)
+	}
+	query := `INSERT INTO undo (user_id, token, task_id, task, completion_id, expires_at) VALUES (?, ?, ?, ?, ?, ?)`
+	_, err = tx.Exec(s.rebind(query), userID, undo.Token, task.ID, string(snapshot), completionID, undo.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("error saving undo: %w", err)
+	}
+	return nil
+}
+
+// Undo отменяет удаление или выполнение задачи по токену: в одной транзакции задача возвращается
+// в сохраненное состояние (задача из корзины восстанавливается, окончательно удаленная создается заново
+// с прежним id), отметка о выполнении удаляется из журнала, а токен становится недействительным.
+// Возвращает восстановленную задачу
+func (s *SQLStore) Undo(userID int64, token string, now time.Time) (*Task, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id, completionID int64
+	var snapshot string
+	query := `SELECT id, task, completion_id FROM undo WHERE user_id = ? AND token = ? AND expires_at > ?`
+	err = tx.QueryRow(s.rebind(query), userID, token, now.UTC().Format(TimestampFormat)).
+		Scan(&id, &snapshot, &completionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUndoNotFound
+		}
+		return nil, fmt.Errorf("error getting undo: %w", err)
+	}
+	task := &Task{}
+	if err := json.Unmarshal([]byte(snapshot), task); err != nil {
+		return nil, fmt.Errorf("error reading undo: %w", err)
+	}
+
+	err = s.txExecAffected(tx, ErrTaskNotFound, "error restoring task", restoreTaskQuery, updateTaskArgs(userID, task)...)
+	if err == ErrTaskNotFound {
+		_, err = tx.Exec(s.rebind(insertTaskWithIDQuery), task.ID, task.Date, task.Title, task.Comment, task.Repeat,
+			task.Until, task.Remaining, task.Time, task.Duration, task.Timezone, task.SnoozedFrom, userID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error restoring task: %w", err)
+	}
+	if completionID > 0 {
+		query := `DELETE FROM completions WHERE id = ? AND user_id = ?`
+		if _, err := tx.Exec(s.rebind(query), completionID, userID); err != nil {
+			return nil, fmt.Errorf("error restoring task: %w", err)
+		}
+	}
+	if _, err := tx.Exec(s.rebind(`DELETE FROM undo WHERE id = ?`), id); err != nil {
+		return nil, fmt.Errorf("error restoring task: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error restoring task: %w", err)
+	}
+	return task, nil
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUserExists - ошибка регистрации пользователя с занятым логином
+var ErrUserExists = errors.New("user already exists")
+
+// User - структура пользователя, соответствует записям в таблице users
+type User struct {
+	ID           int64
+	Login        string
+	PasswordHash string
+}
+
+// AddUser добавляет нового пользователя в БД, возвращает его id
+func (s *SQLStore) AddUser(login, passwordHash string) (int64, error) {
+	query := `INSERT INTO users (login, password_hash) VALUES (?, ?)`
+
+	id, err := s.insert(query, login, passwordHash)
+	if err != nil {
+		// SQLite и PostgreSQL по-разному сообщают о нарушении уникальности, но оба упоминают unique
+		if strings.Contains(strings.ToLower(err.Error()), "unique") {
+			return 0, ErrUserExists
+		}
+		return 0, fmt.Errorf("error adding user: %w", err)
+	}
+	return id, nil
+}
+
+// GetUser получает пользователя по его id
+func (s *SQLStore) GetUser(id int64) (*User, error) {
+	query := `SELECT id, login, password_hash FROM users WHERE id = ?`
+	return scanUser(s.queryRow(query, id))
+}
+
+// GetUserByLogin получает пользователя по логину
+func (s *SQLStore) GetUserByLogin(login string) (*User, error) {
+	query := `SELECT id, login, password_hash FROM users WHERE login = ?`
+	return scanUser(s.queryRow(query, login))
+}
+
+// scanUser сканирует результат запроса в структуру пользователя
+func scanUser(row *sql.Row) (*User, error) {
+	user := &User{}
+	err := row.Scan(&user.ID, &user.Login, &user.PasswordHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+	return user, nil
+}
@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type agendaResp struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Days []struct {
+		Date  string              `json:"date"`
+		Tasks []map[string]string `json:"tasks"`
+	} `json:"days"`
+}
+
+func TestAgenda(t *testing.T) {
+	token := signup(t, fmt.Sprintf("agenda%d", time.Now().UnixNano()), "agenda-password")
+
+	// понедельник через два месяца, чтобы ни одна дата не оказалась в прошлом
+	base := time.Now().AddDate(0, 2, 0)
+	for base.Weekday() != time.Monday {
+		base = base.AddDate(0, 0, 1)
+	}
+	day := func(n int) string { return base.AddDate(0, 0, n).Format(`20060102`) }
+
+	for _, v := range []map[string]any{
+		{"date": day(-10), "title": "Каждые три дня", "repeat": "d 3"},
+		{"date": day(0), "title": "Пн, ср, пт", "repeat": "w 1,3,5"},
+		{"date": day(2), "title": "Каждые пять дней", "repeat": "d 5"},
+		{"date": day(4), "title": "Разовая"},
+		{"date": day(20), "title": "Разовая позже"},
+		{"date": day(100), "title": "Ежегодная", "repeat": "y"},
+	} {
+		code, body := tokenJSON(t, "api/task", token, v, http.MethodPost)
+		assert.Equal(t, http.StatusCreated, code, string(body))
+	}
+
+	code, body := tokenJSON(t, "api/agenda?from="+day(0)+"&to="+base.AddDate(0, 0, 13).Format("02.01.2006"), token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code, string(body))
+	var agenda agendaResp
+	assert.NoError(t, json.Unmarshal(body, &agenda))
+	assert.Equal(t, day(0), agenda.From)
+	assert.Equal(t, day(13), agenda.To)
+
+	got := make(map[string][]string)
+	for _, d := range agenda.Days {
+		for _, task := range d.Tasks {
+			assert.Equal(t, d.Date, task["date"])
+			got[d.Date] = append(got[d.Date], task["title"])
+		}
+	}
+	assert.Equal(t, map[string][]string{
+		day(0):  {"Пн, ср, пт"},
+		day(2):  {"Каждые три дня", "Пн, ср, пт", "Каждые пять дней"},
+		day(4):  {"Пн, ср, пт", "Разовая"},
+		day(5):  {"Каждые три дня"},
+		day(7):  {"Пн, ср, пт", "Каждые пять дней"},
+		day(8):  {"Каждые три дня"},
+		day(9):  {"Пн, ср, пт"},
+		day(11): {"Каждые три дня", "Пн, ср, пт"},
+		day(12): {"Каждые пять дней"},
+	}, got)
+	for i := 1; i < len(agenda.Days); i++ {
+		assert.Less(t, agenda.Days[i-1].Date, agenda.Days[i].Date)
+	}
+
+	for _, params := range []string{"from=someday", "from=" + day(5) + "&to=" + day(1), "to=" + day(400)} {
+		code, _ := tokenJSON(t, "api/agenda?"+params, token, nil, http.MethodGet)
+		assert.Equal(t, http.StatusBadRequest, code, params)
+	}
+}
@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func addAPIToken(t *testing.T, session string, values map[string]any) (string, string) {
+	code, body := tokenJSON(t, "api/tokens", session, values, http.MethodPost)
+	assert.Equal(t, http.StatusCreated, code, string(body))
+
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	token, _ := m["token"].(string)
+	assert.NotEmpty(t, token)
+	return fmt.Sprint(m["id"]), token
+}
+
+func TestAPITokens(t *testing.T) {
+	session := signup(t, fmt.Sprintf("robot%d", time.Now().UnixNano()), "robot-password")
+
+	rwID, rw := addAPIToken(t, session, map[string]any{"name": "cron"})
+	_, ro := addAPIToken(t, session, map[string]any{"name": "dashboard", "read_only": true})
+
+	code, _ := tokenJSON(t, "api/tokens", session, map[string]any{
+		"name":       "old",
+		"expires_at": "2001-01-01T00:00:00Z",
+	}, http.MethodPost)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, body := tokenJSON(t, "api/task", rw, map[string]any{"title": "Задача от бота"}, http.MethodPost)
+	assert.Equal(t, http.StatusCreated, code, string(body))
+
+	code, _ = tokenJSON(t, "api/task", ro, map[string]any{"title": "Нельзя"}, http.MethodPost)
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, body = tokenJSON(t, "api/tasks", ro, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code)
+	var tasks struct {
+		Tasks []map[string]string `json:"tasks"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &tasks))
+	assert.Len(t, tasks.Tasks, 1)
+
+	code, _ = tokenJSON(t, "api/tokens", rw, nil, http.MethodGet)
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, _ = tokenJSON(t, "api/tokens", session, map[string]any{"id": rwID, "name": "cron-nightly"}, http.MethodPut)
+	assert.Equal(t, http.StatusOK, code)
+
+	code, body = tokenJSON(t, "api/tokens", session, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code)
+	var list map[string][]map[string]any
+	assert.NoError(t, json.Unmarshal(body, &list))
+	assert.Len(t, list["tokens"], 2)
+	for _, v := range list["tokens"] {
+		assert.Nil(t, v["token"])
+		assert.NotEmpty(t, v["last_used_at"])
+		if fmt.Sprint(v["id"]) == rwID {
+			assert.Equal(t, "cron-nightly", v["name"])
+		}
+	}
+
+	code, _ = tokenJSON(t, "api/tokens?id="+rwID, session, nil, http.MethodDelete)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = tokenJSON(t, "api/tasks", rw, nil, http.MethodGet)
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
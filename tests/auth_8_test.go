@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func signin(t *testing.T, password string) (int, map[string]any) {
+	data, err := json.Marshal(map[string]any{"password": password})
+	assert.NoError(t, err)
+	resp, err := http.Post(getURL("api/signin"), "application/json", bytes.NewBuffer(data))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return resp.StatusCode, m
+}
+
+func getWithToken(t *testing.T, apipath string, token string) int {
+	req, err := http.NewRequest(http.MethodGet, getURL(apipath), nil)
+	assert.NoError(t, err)
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestAuth(t *testing.T) {
+	password := os.Getenv("TODO_PASSWORD")
+	if len(password) == 0 {
+		t.Skip("TODO_PASSWORD не задан, аутентификация отключена")
+	}
+
+	code, m := signin(t, password+"wrong")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.NotEmpty(t, m["error"])
+
+	assert.Equal(t, http.StatusUnauthorized, getWithToken(t, "api/tasks", ""))
+	assert.Equal(t, http.StatusUnauthorized, getWithToken(t, "api/tasks", "qwerty"))
+
+	code, m = signin(t, password)
+	assert.Equal(t, http.StatusOK, code)
+	token, _ := m["token"].(string)
+	assert.NotEmpty(t, token)
+
+	assert.Equal(t, http.StatusOK, getWithToken(t, "api/tasks", token))
+}
@@ -0,0 +1,276 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eOne007/final-project-yapr/internal/repeater"
+	"github.com/eOne007/final-project-yapr/pkg/api"
+	"github.com/eOne007/final-project-yapr/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBusinessRules(t *testing.T) {
+	date := func(s string) time.Time {
+		d, err := time.Parse("20060102", s)
+		assert.NoError(t, err)
+		return d
+	}
+
+	for _, v := range []struct {
+		repeat, canonical string
+		rule              repeater.Rule
+	}{
+		{"b 3", "b 3", repeater.Business{Interval: 3}},
+		{"bm -1,1", "bm -1,1", repeater.BusinessMonthly{Days: []int{-1, 1}}},
+		{"bm 05 12,3", "bm 5 3,12", repeater.BusinessMonthly{Days: []int{5}, Months: []int{3, 12}}},
+	} {
+		rule, err := repeater.Parse(v.repeat)
+		assert.NoError(t, err, v.repeat)
+		assert.Equal(t, v.rule, rule, v.repeat)
+		if rule != nil {
+			assert.Equal(t, v.canonical, rule.String())
+		}
+	}
+
+	for _, v := range []struct {
+		repeat string
+		pos    int
+		token  string
+	}{
+		{"b 0", 3, "0"},
+		{"b 1 2", 5, "2"},
+		{"bm", 3, "bm"},
+		{"bm 24", 4, "24"},
+		{"bm 1 13", 6, "13"},
+	} {
+		_, err := repeater.Parse(v.repeat)
+		var parseErr *repeater.ParseError
+		if assert.True(t, errors.As(err, &parseErr), "%q: %v", v.repeat, err) {
+			assert.Equal(t, v.pos, parseErr.Pos, v.repeat)
+			assert.Equal(t, v.token, parseErr.Token, v.repeat)
+		}
+	}
+
+	holidays := repeater.Holidays{
+		"20240229": false, "20240427": true, "20240509": false, "20240510": false,
+		"20250101": false, "20250102": false, "20250103": false, "20250106": false,
+		"20250107": false, "20250108": false,
+	}
+	for _, v := range []struct {
+		repeat, after, want string
+		cal                 repeater.Calendar
+	}{
+		{"b 3", "20240126", "20240131", nil},
+		{"b 1", "20240508", "20240513", holidays},
+		{"b 1", "20240426", "20240427", holidays},
+		{"b 1", "20240426", "20240429", nil},
+		{"bm -1", "20240126", "20240131", nil},
+		{"bm -1", "20240201", "20240229", nil},
+		{"bm -1", "20240201", "20240228", holidays},
+		{"bm 1 1", "20240601", "20250109", holidays},
+		{"bm 2,-2 3", "20240101", "20240304", nil},
+	} {
+		rule, err := repeater.Parse(v.repeat)
+		assert.NoError(t, err)
+		next, ok := repeater.UseCalendar(rule, v.cal).Next(date(v.after))
+		assert.True(t, ok)
+		assert.Equal(t, v.want, next.Format("20060102"), "%s after %s", v.repeat, v.after)
+	}
+
+	// в календаре без единого рабочего дня дат по правилу нет
+	never := repeater.Holidays{}
+	for d := date("20240101"); d.Year() < 2026; d = d.AddDate(0, 0, 1) {
+		never[d.Format("20060102")] = false
+	}
+	_, ok := repeater.UseCalendar(repeater.Business{Interval: 1}, never).Next(date("20240101"))
+	assert.False(t, ok)
+}
+
+// TestBusinessAfter сверяет прямое вычисление первой даты после now для правила b
+// с последовательным вычислением дат от начальной по календарю с праздниками и переносами
+func TestBusinessAfter(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	base := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	holidays := repeater.Holidays{}
+	for i := 0; i < 300; i++ {
+		// и праздники в будни, и рабочие выходные, в том числе совпадающие с обычным расписанием
+		holidays[base.AddDate(0, 0, rnd.Intn(365*10)).Format("20060102")] = rnd.Intn(3) == 0
+	}
+	for _, cal := range []repeater.Calendar{nil, holidays} {
+		for _, interval := range []int{1, 2, 5, 7, 23, 400} {
+			rule := repeater.UseCalendar(repeater.Business{Interval: interval}, cal)
+			for i := 0; i < 200; i++ {
+				start := base.AddDate(0, 0, rnd.Intn(365*10))
+				now := base.AddDate(0, 0, rnd.Intn(365*10))
+
+				want, ok := rule.Next(start)
+				for ok && !repeater.AfterNow(want, now) {
+					want, ok = rule.Next(want)
+				}
+				got, gotOK := repeater.After(rule, start, now)
+				assert.Equal(t, ok, gotOK)
+				assert.Equal(t, want.Format("20060102"), got.Format("20060102"),
+					"b %d start %s now %s", interval, start.Format("20060102"), now.Format("20060102"))
+			}
+		}
+	}
+}
+
+// holidaysSpy - хранилище в памяти, которое запоминает периоды запросов календаря праздников
+type holidaysSpy struct {
+	*db.MemoryStore
+	periods []string
+}
+
+func (h *holidaysSpy) Holidays(userID int64, from, to string) ([]*db.Holiday, error) {
+	h.periods = append(h.periods, from+"-"+to)
+	return h.MemoryStore.Holidays(userID, from, to)
+}
+
+// TestCalendarLoading проверяет, что календарь праздников загружается только для правил b и bm
+// и только за период от начальной даты до now с запасом
+func TestCalendarLoading(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "")
+
+	store := &holidaysSpy{MemoryStore: db.NewMemoryStore()}
+	srv := httptest.NewServer(api.NewServer(store))
+	defer srv.Close()
+
+	nextDate := func(date, repeat string) string {
+		values := url.Values{"date": {date}, "repeat": {repeat}, "now": {"20261017"}}
+		resp, err := srv.Client().Get(srv.URL + "/api/nextdate?" + values.Encode())
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		return string(body)
+	}
+
+	assert.Equal(t, "20261018", nextDate("20240101", "d 1"))
+	assert.Equal(t, "20261021", nextDate("20240101", "w 3"))
+	code, _ := serverJSON(t, srv, "api/task", map[string]any{"date": "20240101", "title": "Отчет", "repeat": "m 1"},
+		http.MethodPost)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Empty(t, store.periods)
+
+	assert.Equal(t, "20261019", nextDate("20240115", "b 1"))
+	assert.Equal(t, []string{"20240101-20281018"}, store.periods)
+}
+
+func TestHolidaysAPI(t *testing.T) {
+	token := signup(t, fmt.Sprintf("holidays%d", time.Now().UnixNano()), "holidays-password")
+
+	importFile := func(format, body string) (int, []byte) {
+		req, err := http.NewRequest(http.MethodPost, getURL("api/holidays/import?format="+format), bytes.NewBufferString(body))
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		return resp.StatusCode, data
+	}
+	list := func(query string) []map[string]any {
+		code, body := tokenJSON(t, "api/holidays"+query, token, nil, http.MethodGet)
+		assert.Equal(t, http.StatusOK, code, string(body))
+		var resp struct {
+			Holidays []map[string]any `json:"holidays"`
+		}
+		assert.NoError(t, json.Unmarshal(body, &resp))
+		return resp.Holidays
+	}
+	nextDate := func(date, repeat string) string {
+		code, body := tokenJSON(t, fmt.Sprintf("api/nextdate?now=%s&date=%s&repeat=%s",
+			date, date, url.QueryEscape(repeat)), token, nil, http.MethodGet)
+		assert.Equal(t, http.StatusOK, code, string(body))
+		return string(body)
+	}
+
+	code, body := tokenJSON(t, "api/holidays", token, map[string]any{
+		"date": "09.05.2024", "name": "День Победы",
+	}, http.MethodPost)
+	assert.Equal(t, http.StatusCreated, code, string(body))
+	assert.Equal(t, "20240510", nextDate("20240508", "b 1"))
+
+	code, body = importFile("csv", "date,name,working\n2024-05-10,Перенос\n20240511,Рабочая суббота,true\n")
+	assert.Equal(t, http.StatusOK, code, string(body))
+	assert.JSONEq(t, `{"imported": 2}`, string(body))
+	assert.Equal(t, "20240511", nextDate("20240508", "b 1"))
+
+	ics := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20250101\r\nDTEND;VALUE=DATE:20250109\r\n" +
+		"SUMMARY:Новогодние\r\n  каникулы\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	code, body = importFile("ics", ics)
+	assert.Equal(t, http.StatusOK, code, string(body))
+	assert.JSONEq(t, `{"imported": 8}`, string(body))
+	assert.Equal(t, "20250109", nextDate("20241231", "b 1"))
+	assert.Equal(t, "20250109", nextDate("20241231", "bm 1"))
+
+	january := list("?from=20250101&to=20250131")
+	assert.Len(t, january, 8)
+	if len(january) > 0 {
+		assert.Equal(t, "Новогодние каникулы", january[0]["name"])
+	}
+	assert.Len(t, list(""), 11)
+
+	code, _ = tokenJSON(t, "api/holidays?date=20250101", token, nil, http.MethodDelete)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = tokenJSON(t, "api/holidays?date=20250101", token, nil, http.MethodDelete)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// повторяющееся событие разворачивается в дни каждого повторения
+	ics = "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20300308\r\nDTEND;VALUE=DATE:20300310\r\n" +
+		"RRULE:FREQ=YEARLY;COUNT=3\r\nSUMMARY:Весенние выходные\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	code, body = importFile("ics", ics)
+	assert.Equal(t, http.StatusOK, code, string(body))
+	assert.JSONEq(t, `{"imported": 6}`, string(body))
+	var dates []string
+	for _, day := range list("?from=20300101&to=20331231") {
+		dates = append(dates, fmt.Sprint(day["date"]))
+	}
+	assert.Equal(t, []string{"20300308", "20300309", "20310308", "20310309", "20320308", "20320309"}, dates)
+
+	// в ошибке указывается номер строки файла, а не строки после объединения перенесенных строк
+	code, body = importFile("ics", "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20300101\nSUMMARY:Длинное\n  название\n  события\n"+
+		"RRULE:FREQ=YEARLY\nEND:VEVENT\nEND:VCALENDAR\n")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, string(body), "line 8")
+	assert.Contains(t, string(body), "COUNT or UNTIL")
+	code, body = importFile("ics", "BEGIN:VEVENT\nDTSTART:20300101\nRRULE:FREQ=YEARLY;COUNT=2\nEXDATE:20310101\nEND:VEVENT\n")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, string(body), "EXDATE")
+
+	// слишком длинные названия и слишком большие календари отклоняются с номером строки
+	long := strings.Repeat("x", 256)
+	code, body = importFile("ics", "BEGIN:VEVENT\nDTSTART:20300101\nSUMMARY:"+long+"\nEND:VEVENT\n")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, string(body), "line 4")
+	assert.Contains(t, string(body), "SUMMARY")
+	code, body = importFile("csv", "20300101,Праздник\n20300102,"+long+"\n")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, string(body), "line 2")
+	code, body = importFile("ics", "BEGIN:VEVENT\nDTSTART:20300101\nDTEND:20301231\nRRULE:FREQ=YEARLY;COUNT=100\nEND:VEVENT\n")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, string(body), "line 5")
+	assert.Contains(t, string(body), "more than")
+
+	code, _ = importFile("csv", "20240101,Новый год\nвчера,Ошибка\n")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = importFile("ics", "BEGIN:VEVENT\nSUMMARY:Без даты\nEND:VEVENT\n")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = importFile("xml", "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = tokenJSON(t, "api/holidays", token, map[string]any{"date": "31.02.2024"}, http.MethodPost)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
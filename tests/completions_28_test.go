@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompletions(t *testing.T) {
+	token := signup(t, fmt.Sprintf("history%d", time.Now().UnixNano()), "history-password")
+	started := time.Now().UTC().Add(-time.Second)
+
+	done := func(id string) {
+		code, body := tokenJSON(t, "api/task/done?id="+id, token, nil, http.MethodPost)
+		assert.Equal(t, http.StatusOK, code, string(body))
+	}
+	type completion struct {
+		ID       string `json:"id"`
+		TaskID   string `json:"task_id"`
+		Title    string `json:"title"`
+		Date     string `json:"date"`
+		DoneAt   string `json:"done_at"`
+		NextDate string `json:"next_date"`
+	}
+	list := func(apipath string) []completion {
+		code, body := tokenJSON(t, apipath, token, nil, http.MethodGet)
+		assert.Equal(t, http.StatusOK, code, string(body))
+		var resp struct {
+			Completions []completion `json:"completions"`
+		}
+		assert.NoError(t, json.Unmarshal(body, &resp))
+		return resp.Completions
+	}
+
+	recurring := addTokenTask(t, token, map[string]any{"title": "Полив", "repeat": "d 2"})
+	task := getTokenTask(t, token, recurring)
+	today, _ := task["date"].(string)
+	day, err := time.Parse(`20060102`, today)
+	assert.NoError(t, err)
+
+	// после первого выполнения задача переименована, в журнале остается прежний заголовок
+	done(recurring)
+	task["title"] = "Полив цветов"
+	task["date"] = day.AddDate(0, 0, 2).Format(`20060102`)
+	code, body := tokenJSON(t, "api/task", token, task, http.MethodPut)
+	assert.Equal(t, http.StatusOK, code, string(body))
+	done(recurring)
+
+	once := addTokenTask(t, token, map[string]any{"title": "Разовая"})
+	done(once)
+
+	history := list("api/task/history?id=" + recurring)
+	if assert.Len(t, history, 2) {
+		assert.Equal(t, "Полив", history[0].Title)
+		assert.Equal(t, today, history[0].Date)
+		assert.Equal(t, day.AddDate(0, 0, 2).Format(`20060102`), history[0].NextDate)
+		assert.Equal(t, "Полив цветов", history[1].Title)
+		assert.Equal(t, history[0].NextDate, history[1].Date)
+		assert.Equal(t, day.AddDate(0, 0, 4).Format(`20060102`), history[1].NextDate)
+		for _, c := range history {
+			assert.Equal(t, recurring, c.TaskID)
+			doneAt, err := time.Parse(time.RFC3339, c.DoneAt)
+			assert.NoError(t, err)
+			assert.False(t, doneAt.Before(started), c.DoneAt)
+		}
+	}
+
+	// история удаленной разовой задачи остается доступной
+	history = list("api/task/history?id=" + once)
+	if assert.Len(t, history, 1) {
+		assert.Equal(t, "Разовая", history[0].Title)
+		assert.Empty(t, history[0].NextDate)
+	}
+	code, _ = tokenJSON(t, "api/task?id="+once, token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	fresh := addTokenTask(t, token, map[string]any{"title": "Без выполнений"})
+	assert.Empty(t, list("api/task/history?id="+fresh))
+	code, _ = tokenJSON(t, "api/task/history?id=999999999", token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = tokenJSON(t, "api/task/history", token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// журнал за период: по умолчанию - последние 30 дней
+	var titles []string
+	for _, c := range list("api/completions") {
+		titles = append(titles, c.Title)
+	}
+	assert.Equal(t, []string{"Полив", "Полив цветов", "Разовая"}, titles)
+	assert.Len(t, list("api/completions?from=today&to=today"), 3)
+	assert.Empty(t, list("api/completions?from=tomorrow&to=tomorrow"))
+	assert.Empty(t, list("api/completions?to=yesterday"))
+	code, _ = tokenJSON(t, "api/completions?from=today&to=yesterday", token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = tokenJSON(t, "api/completions?from=20240101&to=20250601", token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// журнал другого пользователя не виден
+	other := signup(t, fmt.Sprintf("history_other%d", time.Now().UnixNano()), "history-password")
+	code, body = tokenJSON(t, "api/completions", other, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code, string(body))
+	assert.JSONEq(t, `[]`, string(mustField(t, body, "completions")))
+	code, _ = tokenJSON(t, "api/task/history?id="+recurring, other, nil, http.MethodGet)
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+// mustField возвращает значение поля JSON-объекта body
+func mustField(t *testing.T, body []byte, name string) json.RawMessage {
+	var m map[string]json.RawMessage
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return m[name]
+}
@@ -6,16 +6,25 @@ import (
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	_ "modernc.org/sqlite"
 )
 
 type Task struct {
-	ID      int64  `db:"id"`
-	Date    string `db:"date"`
-	Title   string `db:"title"`
-	Comment string `db:"comment"`
-	Repeat  string `db:"repeat"`
+	ID        int64  `db:"id"`
+	Date      string `db:"date"`
+	Title     string `db:"title"`
+	Comment   string `db:"comment"`
+	Repeat    string `db:"repeat"`
+	UserID    int64  `db:"user_id"`
+	Until     string `db:"until_date"`
+	Remaining int    `db:"remaining"`
+	StartTime string `db:"start_time"`
+	Duration  int    `db:"duration"`
+	Timezone  string `db:"timezone"`
+	DeletedAt string `db:"deleted_at"`
+	Snoozed   string `db:"snoozed_from"`
 }
 
 func count(db *sqlx.DB) (int, error) {
@@ -24,12 +33,22 @@ func count(db *sqlx.DB) (int, error) {
 }
 
 func openDB(t *testing.T) *sqlx.DB {
-	dbfile := DBFile
-	envFile := os.Getenv("TODO_DBFILE")
-	if len(envFile) > 0 {
-		dbfile = envFile
+	driver := DBDriver
+	if envDriver := os.Getenv("TODO_DB_DRIVER"); len(envDriver) > 0 {
+		driver = envDriver
 	}
-	db, err := sqlx.Connect("sqlite", dbfile)
+	dsn := DBDSN
+	if envDSN := os.Getenv("TODO_DB_DSN"); len(envDSN) > 0 {
+		dsn = envDSN
+	}
+	if driver == "sqlite" && len(dsn) == 0 {
+		dsn = DBFile
+		envFile := os.Getenv("TODO_DBFILE")
+		if len(envFile) > 0 {
+			dsn = envFile
+		}
+	}
+	db, err := sqlx.Connect(driver, dsn)
 	assert.NoError(t, err)
 	return db
 }
@@ -43,20 +62,19 @@ func TestDB(t *testing.T) {
 
 	today := time.Now().Format(`20060102`)
 
-	res, err := db.Exec(`INSERT INTO scheduler (date, title, comment, repeat) 
-	VALUES (?, 'Todo', 'Комментарий', '')`, today)
+	var id int64
+	err = db.QueryRow(db.Rebind(`INSERT INTO scheduler (date, title, comment, repeat) 
+	VALUES (?, 'Todo', 'Комментарий', '') RETURNING id`), today).Scan(&id)
 	assert.NoError(t, err)
 
-	id, err := res.LastInsertId()
-
 	var task Task
-	err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+	err = db.Get(&task, db.Rebind(`SELECT * FROM scheduler WHERE id=?`), id)
 	assert.NoError(t, err)
 	assert.Equal(t, id, task.ID)
 	assert.Equal(t, `Todo`, task.Title)
 	assert.Equal(t, `Комментарий`, task.Comment)
 
-	_, err = db.Exec(`DELETE FROM scheduler WHERE id = ?`, id)
+	_, err = db.Exec(db.Rebind(`DELETE FROM scheduler WHERE id = ?`), id)
 	assert.NoError(t, err)
 
 	after, err := count(db)
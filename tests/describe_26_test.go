@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/eOne007/final-project-yapr/internal/repeater"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribe(t *testing.T) {
+	for _, v := range []struct {
+		repeat string
+		ru, en string
+	}{
+		{"d 1", "каждый день", "every day"},
+		{"d 3", "каждые 3 дня", "every 3 days"},
+		{"d 5", "каждые 5 дней", "every 5 days"},
+		{"d 21", "каждый 21 день", "every 21 days"},
+		{"d 12", "каждые 12 дней", "every 12 days"},
+		{"w 1,4", "по понедельникам и четвергам", "on Monday and Thursday"},
+		{"w 7,6,5", "по пятницам, субботам и воскресеньям", "on Friday, Saturday and Sunday"},
+		{"w 1,2,3,4,5,6,7", "каждый день", "every day"},
+		{"w 3 /2", "раз в 2 недели по средам", "every 2 weeks on Wednesday"},
+		{"m 1,-1 3,6", "1-го и в последний день марта и июня", "on the 1st and last day of March and June"},
+		{"m 15", "15-го числа каждого месяца", "on the 15th of every month"},
+		{"m -2,22,1", "1-го, 22-го и в предпоследний день каждого месяца",
+			"on the 1st, 22nd and second-to-last day of every month"},
+		{"m 2tue", "во второй вторник каждого месяца", "on the second Tuesday of every month"},
+		{"m -1fri 12", "в последнюю пятницу декабря", "on the last Friday of December"},
+		{"m 1sun,-3wed", "в первое воскресенье и в третью с конца среду каждого месяца",
+			"on the first Sunday and third-to-last Wednesday of every month"},
+		{"y", "каждый год", "every year"},
+		{"b 1", "каждый рабочий день", "every working day"},
+		{"b 2", "каждые 2 рабочих дня", "every 2 working days"},
+		{"bm 1,-1", "в 1-й и в последний рабочий день каждого месяца", "on the 1st and last working day of every month"},
+		{"bm 3 1,7", "в 3-й рабочий день января и июля", "on the 3rd working day of January and July"},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", "раз в 2 недели по понедельникам и четвергам",
+			"every 2 weeks on Monday and Thursday"},
+		{"FREQ=MONTHLY;BYDAY=-1FR;COUNT=5", "каждый месяц в последнюю пятницу, 5 раз",
+			"every month on the last Friday, 5 times"},
+		{"FREQ=YEARLY;BYMONTH=3;BYMONTHDAY=8;UNTIL=20301231", "каждый год 8-го числа в марте, до 31.12.2030",
+			"every year on the 8th in March, until December 31, 2030"},
+		{"FREQ=YEARLY;BYDAY=20MO", "каждый год в 20-й понедельник", "every year on the 20th Monday"},
+		{"FREQ=YEARLY;BYDAY=-20MO", "каждый год в 20-й с конца понедельник", "every year on the 20th-to-last Monday"},
+	} {
+		rule, err := repeater.Parse(v.repeat)
+		if !assert.NoError(t, err, v.repeat) {
+			continue
+		}
+		assert.Equal(t, v.ru, repeater.Describe(rule, repeater.LangRussian), v.repeat)
+		assert.Equal(t, v.en, repeater.Describe(rule, repeater.LangEnglish), v.repeat)
+	}
+}
+
+func TestRepeatText(t *testing.T) {
+	token := signup(t, fmt.Sprintf("describe%d", time.Now().UnixNano()), "describe-password")
+	day := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+	code, body := tokenJSON(t, "api/task", token, map[string]any{
+		"date": day, "title": "Отчет", "repeat": "m 1,-1 3,6",
+	}, http.MethodPost)
+	assert.Equal(t, http.StatusCreated, code, string(body))
+	var created map[string]any
+	assert.NoError(t, json.Unmarshal(body, &created))
+	id, _ := created["id"].(string)
+
+	get := func(apipath, lang string) map[string]any {
+		req, err := http.NewRequest(http.MethodGet, getURL(apipath), nil)
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if lang != "" {
+			req.Header.Set("Accept-Language", lang)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+		var m map[string]any
+		assert.NoError(t, json.Unmarshal(body, &m))
+		return m
+	}
+
+	ru := "1-го и в последний день марта и июня"
+	en := "on the 1st and last day of March and June"
+	for _, v := range []struct{ lang, want string }{
+		{"", ru},
+		{"en-US,en;q=0.9", en},
+		{"de-DE,de;q=0.9,en;q=0.8,ru;q=0.7", en},
+		{"en;q=0.5,ru-RU", ru},
+		{"fr", ru},
+	} {
+		task := get("api/task?id="+id, v.lang)
+		assert.Equal(t, v.want, task["repeat_text"], v.lang)
+
+		list := get("api/tasks", v.lang)
+		tasks, _ := list["tasks"].([]any)
+		if assert.Len(t, tasks, 1) {
+			item, _ := tasks[0].(map[string]any)
+			assert.Equal(t, v.want, item["repeat_text"], v.lang)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepeatEnd(t *testing.T) {
+	token := signup(t, fmt.Sprintf("repeatend%d", time.Now().UnixNano()), "repeatend-password")
+	day := func(n int) string { return time.Now().AddDate(0, 0, n).Format(`20060102`) }
+
+	done := func(id string) {
+		code, body := tokenJSON(t, "api/task/done?id="+id, token, nil, http.MethodPost)
+		assert.Equal(t, http.StatusOK, code, string(body))
+	}
+
+	// оставшееся число выполнений уменьшается при каждой отметке, после последней задача удаляется
+	id := addTokenTask(t, token, map[string]any{"date": day(0), "title": "Принять лекарство", "repeat": "d 1", "remaining": 3})
+	for i := 1; i <= 2; i++ {
+		done(id)
+		code, task := tokenMap(t, "api/task?id="+id, token, nil, http.MethodGet)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, day(i), task["date"])
+		assert.Equal(t, float64(3-i), task["remaining"])
+	}
+	done(id)
+	code, _ := tokenMap(t, "api/task?id="+id, token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// задача удаляется, когда следующая дата оказывается позже даты окончания
+	id = addTokenTask(t, token, map[string]any{"date": day(0), "title": "Курс до конца недели", "repeat": "d 3", "until": day(5)})
+	done(id)
+	code, task := tokenMap(t, "api/task?id="+id, token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, day(3), task["date"])
+	assert.Equal(t, day(5), task["until"])
+	done(id)
+	code, _ = tokenMap(t, "api/task?id="+id, token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// повестка не выходит за условия окончания
+	addTokenTask(t, token, map[string]any{"date": day(1), "title": "Три раза", "repeat": "d 2", "remaining": 3})
+	addTokenTask(t, token, map[string]any{"date": day(1), "title": "До даты", "repeat": "d 1", "until": day(3)})
+	code, body := tokenJSON(t, fmt.Sprintf("api/agenda?from=%s&to=%s", day(0), day(13)), token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code, string(body))
+	var agenda struct {
+		Days []struct {
+			Date  string           `json:"date"`
+			Tasks []map[string]any `json:"tasks"`
+		} `json:"days"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &agenda))
+	got := map[string][]string{}
+	for _, d := range agenda.Days {
+		for _, task := range d.Tasks {
+			title, _ := task["title"].(string)
+			got[title] = append(got[title], d.Date)
+		}
+	}
+	assert.Equal(t, []string{day(1), day(3), day(5)}, got["Три раза"])
+	assert.Equal(t, []string{day(1), day(2), day(3)}, got["До даты"])
+
+	// изменение задачи без полей окончания их не сбрасывает, переданные пустые значения - сбрасывают
+	id = addTokenTask(t, token, map[string]any{"date": day(1), "title": "Курс", "repeat": "d 1", "until": day(9), "remaining": 5})
+	update := func(values map[string]any) map[string]any {
+		values["id"], values["date"], values["comment"], values["repeat"] = id, day(2), "", "d 1"
+		code, body := tokenJSON(t, "api/task", token, values, http.MethodPut)
+		assert.Equal(t, http.StatusOK, code, string(body))
+		return getTokenTask(t, token, id)
+	}
+	task = update(map[string]any{"title": "Курс лечения"})
+	assert.Equal(t, "Курс лечения", task["title"])
+	assert.Equal(t, day(9), task["until"])
+	assert.Equal(t, float64(5), task["remaining"])
+	task = update(map[string]any{"title": "Курс лечения", "until": "", "remaining": 0})
+	assert.Nil(t, task["until"])
+	assert.Nil(t, task["remaining"])
+
+	for _, values := range []map[string]any{
+		{"date": day(0), "title": "Без правила", "remaining": 2},
+		{"date": day(0), "title": "Без правила", "until": day(3)},
+		{"date": day(0), "title": "Отрицательное", "repeat": "d 1", "remaining": -1},
+		{"date": day(5), "title": "Раньше даты", "repeat": "d 1", "until": day(4)},
+		{"date": day(0), "title": "Неверная дата", "repeat": "d 1", "until": "31.12.2030"},
+	} {
+		code, body := tokenJSON(t, "api/task", token, values, http.MethodPost)
+		assert.Equal(t, http.StatusBadRequest, code, string(body))
+	}
+}
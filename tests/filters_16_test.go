@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/eOne007/final-project-yapr/pkg/api"
+	"github.com/eOne007/final-project-yapr/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListFilters(t *testing.T) {
+	login := fmt.Sprintf("filters%d", time.Now().UnixNano())
+	token := signup(t, login, "filters-password")
+	now := time.Now()
+	day := func(n int) string { return now.AddDate(0, 0, n).Format(`20060102`) }
+
+	for _, v := range []map[string]any{
+		{"date": day(0), "title": "Сегодня", "repeat": "d 1"},
+		{"date": day(2), "title": "Через два дня", "repeat": "w 1,2,3"},
+		{"date": day(6), "title": "Через неделю"},
+		{"date": day(30), "title": "Через месяц", "repeat": "y"},
+	} {
+		code, body := tokenJSON(t, "api/task", token, v, http.MethodPost)
+		assert.Equal(t, http.StatusCreated, code, string(body))
+	}
+
+	// просроченную задачу нельзя создать через API, добавляем ее напрямую в БД
+	db := openDB(t)
+	defer db.Close()
+	var uid int64
+	assert.NoError(t, db.Get(&uid, db.Rebind(`SELECT id FROM users WHERE login = ?`), login))
+	_, err := db.Exec(db.Rebind(`INSERT INTO scheduler (date, title, comment, repeat, user_id) VALUES (?, ?, '', '', ?)`),
+		day(-3), "Просроченная", uid)
+	assert.NoError(t, err)
+
+	titles := func(params url.Values) []string {
+		var list []string
+		for _, task := range getPage(t, token, params).Tasks {
+			list = append(list, task["title"])
+		}
+		return list
+	}
+
+	assert.Equal(t, []string{"Сегодня", "Через два дня", "Через неделю"},
+		titles(url.Values{"from": {"today"}, "to": {day(6)}}))
+	assert.Equal(t, []string{"Через неделю", "Через месяц"},
+		titles(url.Values{"from": {now.AddDate(0, 0, 3).Format("02.01.2006")}}))
+	assert.Equal(t, []string{"Просроченная"}, titles(url.Values{"overdue": {"true"}}))
+	assert.Len(t, titles(url.Values{"overdue": {"false"}}), 5)
+	assert.Equal(t, []string{"Просроченная", "Через неделю"}, titles(url.Values{"repeat": {"none"}}))
+	assert.Equal(t, []string{"Сегодня", "Через два дня", "Через месяц"}, titles(url.Values{"repeat": {"any"}}))
+	assert.Equal(t, []string{"Через два дня"}, titles(url.Values{"repeat": {"w"}, "to": {day(7)}}))
+
+	page := getPage(t, token, url.Values{"search": {"через"}, "to": {day(6)}})
+	assert.Equal(t, 2, page.Total)
+	if assert.NotEmpty(t, page.Tasks) {
+		assert.Contains(t, page.Tasks[0]["snippet"], "<mark>")
+	}
+	assert.Equal(t, []string{"Через месяц"}, titles(url.Values{"search": {"repeat:any -title:сегодня"}, "from": {day(3)}}))
+
+	// правила RRULE находятся по букве правила с той же частотой
+	for _, v := range []map[string]any{
+		{"date": day(40), "title": "Еженедельно RRULE", "repeat": "RRULE:FREQ=WEEKLY;BYDAY=MO,FR"},
+		{"date": day(41), "title": "Ежедневно RRULE", "repeat": "FREQ=DAILY"},
+		{"date": day(42), "title": "Ежемесячно RRULE", "repeat": "FREQ=MONTHLY;INTERVAL=2"},
+	} {
+		code, body := tokenJSON(t, "api/task", token, v, http.MethodPost)
+		assert.Equal(t, http.StatusCreated, code, string(body))
+	}
+	from := url.Values{"from": {day(40)}}
+	for repeat, want := range map[string][]string{
+		"w":   {"Еженедельно RRULE"},
+		"d":   {"Ежедневно RRULE"},
+		"m":   {"Ежемесячно RRULE"},
+		"y":   nil,
+		"any": {"Еженедельно RRULE", "Ежедневно RRULE", "Ежемесячно RRULE"},
+	} {
+		assert.Equal(t, want, titles(url.Values{"repeat": {repeat}, "from": from["from"]}), repeat)
+		assert.Equal(t, want, titles(url.Values{"search": {"repeat:" + repeat}, "from": from["from"]}), repeat)
+	}
+
+	for _, params := range []string{"from=someday", "to=31.02.2026", "overdue=maybe", "repeat=q"} {
+		code, _ := tokenJSON(t, "api/tasks?"+params, token, nil, http.MethodGet)
+		assert.Equal(t, http.StatusBadRequest, code, params)
+	}
+}
+
+func TestRepeatFilterMemory(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "")
+	srv := httptest.NewServer(api.NewServer(db.NewMemoryStore()))
+	defer srv.Close()
+
+	day := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+	for _, repeat := range []string{"w 1", "FREQ=WEEKLY;BYDAY=TU", "FREQ=YEARLY", "d 3"} {
+		code, m := serverJSON(t, srv, "api/task", map[string]any{"date": day, "title": repeat, "repeat": repeat}, http.MethodPost)
+		assert.Equal(t, http.StatusCreated, code, m)
+	}
+	titles := func(query string) []string {
+		code, m := serverJSON(t, srv, "api/tasks?"+query, nil, http.MethodGet)
+		assert.Equal(t, http.StatusOK, code, m)
+		var list []string
+		for _, task := range m["tasks"].([]any) {
+			list = append(list, task.(map[string]any)["title"].(string))
+		}
+		return list
+	}
+
+	assert.ElementsMatch(t, []string{"w 1", "FREQ=WEEKLY;BYDAY=TU"}, titles("repeat=w"))
+	assert.ElementsMatch(t, []string{"FREQ=YEARLY"}, titles("repeat=y"))
+	assert.ElementsMatch(t, []string{"w 1", "FREQ=WEEKLY;BYDAY=TU", "FREQ=YEARLY"},
+		titles("search="+url.QueryEscape("repeat:w OR repeat:y")))
+	assert.Empty(t, titles("repeat=m"))
+}
@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eOne007/final-project-yapr/pkg/db"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+)
+
+func TestMigrate(t *testing.T) {
+	dbfile := filepath.Join(t.TempDir(), "legacy.db")
+
+	legacy, err := sqlx.Connect("sqlite", dbfile)
+	assert.NoError(t, err)
+	_, err = legacy.Exec(`CREATE TABLE scheduler (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    date CHAR(8) NOT NULL DEFAULT "",
+    title VARCHAR(255) NOT NULL DEFAULT "",
+	comment TEXT,
+	repeat VARCHAR(128) NOT NULL DEFAULT "");
+	CREATE INDEX IF NOT EXISTS idx_date ON scheduler(date);
+	INSERT INTO scheduler (date, title, comment, repeat) VALUES ('20240101', 'Старая задача', '', 'y');`)
+	assert.NoError(t, err)
+	assert.NoError(t, legacy.Close())
+
+	migrations, err := db.Migrations(db.DriverSQLite)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, migrations)
+
+	store, err := db.NewSQLiteStore(dbfile)
+	assert.NoError(t, err)
+	version, err := store.SchemaVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, len(migrations), version)
+
+	status, err := store.Status()
+	assert.NoError(t, err)
+	for _, m := range status {
+		assert.True(t, m.Applied, "миграция %04d_%s не применена", m.Version, m.Name)
+	}
+
+	page, err := store.List(0, db.Page{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, page.Tasks, 1)
+	assert.Equal(t, 1, page.Total)
+
+	assert.NoError(t, store.Close())
+
+	raw, err := sqlx.Connect("sqlite", dbfile)
+	assert.NoError(t, err)
+	_, err = raw.Exec(`PRAGMA user_version = 999`)
+	assert.NoError(t, err)
+	assert.NoError(t, raw.Close())
+
+	_, err = db.NewSQLiteStore(dbfile)
+	assert.ErrorIs(t, err, db.ErrSchemaTooNew)
+}
+
+// TestMigratePostgresConcurrent запускается только с PostgreSQL из TODO_DB_DRIVER и TODO_DB_DSN:
+// несколько экземпляров сервера одновременно применяют миграции к пустой схеме
+func TestMigratePostgresConcurrent(t *testing.T) {
+	dsn := os.Getenv("TODO_DB_DSN")
+	if os.Getenv("TODO_DB_DRIVER") != db.DriverPostgres || dsn == "" {
+		t.Skip("set TODO_DB_DRIVER=postgres and TODO_DB_DSN to run")
+	}
+	conn, err := sqlx.Connect("postgres", dsn)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	schema := fmt.Sprintf("migrate_test_%d", time.Now().UnixNano())
+	_, err = conn.Exec("CREATE SCHEMA " + schema)
+	assert.NoError(t, err)
+	defer conn.Exec("DROP SCHEMA " + schema + " CASCADE")
+
+	// соединения экземпляров работают в новой схеме
+	schemaDSN := dsn + " search_path=" + schema
+	if strings.Contains(dsn, "://") {
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		schemaDSN = dsn + sep + "search_path=" + schema
+	}
+
+	errs := make([]error, 4)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			store, err := db.NewPostgresStore(schemaDSN)
+			if err == nil {
+				store.Close()
+			}
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+
+	migrations, err := db.Migrations(db.DriverPostgres)
+	assert.NoError(t, err)
+	var versions []int
+	assert.NoError(t, conn.Select(&versions, "SELECT version FROM "+schema+".schema_migrations ORDER BY version"))
+	if assert.Len(t, versions, len(migrations)) {
+		assert.Equal(t, len(migrations), versions[len(versions)-1])
+	}
+}
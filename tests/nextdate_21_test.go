@@ -0,0 +1,47 @@
+package tests
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDateNthWeekday(t *testing.T) {
+	if !FullNextDate {
+		return
+	}
+	tbl := []nextDate{
+		{"20240126", "m 2tue", "20240213"},
+		{"20240126", "m 2TUE", "20240213"},
+		{"20240126", "m -1fri", "20240223"},
+		{"20240126", "m -1fri 3,6,9,12", "20240329"},
+		{"20231201", "m 1mon", "20240205"},
+		{"20240126", "m 5thu 2", "20240229"},
+		{"20240126", "m 5mon 2", "20440229"},
+		{"20240126", "m 15,1sun", "20240204"},
+		{"20240126", "m -2wed,-1", "20240131"},
+		{"20240126", "m 6tue", ""},
+		{"20240126", "m 0fri", ""},
+		{"20240126", "m -6fri", ""},
+		{"20240126", "m 2xyz", ""},
+		{"20240126", "m tue", ""},
+		{"20240126", "m 2tue 13", ""},
+	}
+	for _, v := range tbl {
+		urlPath := fmt.Sprintf("api/nextdate?now=20240126&date=%s&repeat=%s",
+			url.QueryEscape(v.date), url.QueryEscape(v.repeat))
+		get, err := getBody(urlPath)
+		assert.NoError(t, err)
+		next := strings.TrimSpace(string(get))
+		_, err = time.Parse("20060102", next)
+		if err != nil && len(v.want) == 0 {
+			continue
+		}
+		assert.Equal(t, v.want, next, `{%q, %q, %q}`,
+			v.date, v.repeat, v.want)
+	}
+}
@@ -0,0 +1,51 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDates(t *testing.T) {
+	token := signup(t, fmt.Sprintf("nextdates%d", time.Now().UnixNano()), "nextdates-password")
+
+	tbl := []struct {
+		date, repeat, n string
+		want            []string
+	}{
+		{"20240113", "d 7", "3", []string{"20240127", "20240203", "20240210"}},
+		{"20240126", "w 1,3", "3", []string{"20240129", "20240131", "20240205"}},
+		{"20240126", "m -1", "3", []string{"20240131", "20240229", "20240331"}},
+		{"20240229", "y", "2", []string{"20250301", "20260301"}},
+		{"", "d 2", "2", []string{"20240128", "20240130"}},
+		{"20240101", "d 1", "", []string{"20240127", "20240128", "20240129", "20240130", "20240131"}},
+	}
+	for _, v := range tbl {
+		path := fmt.Sprintf("api/nextdates?now=20240126&date=%s&repeat=%s&n=%s",
+			v.date, url.QueryEscape(v.repeat), v.n)
+		code, body := tokenJSON(t, path, token, nil, http.MethodGet)
+		assert.Equal(t, http.StatusOK, code, string(body))
+
+		var resp struct {
+			Dates []string `json:"dates"`
+		}
+		assert.NoError(t, json.Unmarshal(body, &resp))
+		assert.Equal(t, v.want, resp.Dates, "%s %q", v.date, v.repeat)
+	}
+
+	for _, query := range []string{
+		"now=20240126&date=20240126",
+		"now=20240126&date=20240126&repeat=k+3",
+		"now=20240126&date=20240126&repeat=d+1&n=0",
+		"now=20240126&date=20240126&repeat=d+1&n=101",
+		"now=ooops&repeat=d+1",
+	} {
+		code, _ := tokenJSON(t, "api/nextdates?"+query, token, nil, http.MethodGet)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+	}
+}
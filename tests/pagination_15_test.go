@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/eOne007/final-project-yapr/pkg/api"
+	"github.com/eOne007/final-project-yapr/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+type tasksPage struct {
+	Tasks      []map[string]string `json:"tasks"`
+	NextCursor string              `json:"next_cursor"`
+	Total      int                 `json:"total"`
+}
+
+func getPage(t *testing.T, token string, params url.Values) tasksPage {
+	code, body := tokenJSON(t, "api/tasks?"+params.Encode(), token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code, string(body))
+
+	var page tasksPage
+	assert.NoError(t, json.Unmarshal(body, &page))
+	return page
+}
+
+func TestPagination(t *testing.T) {
+	token := signup(t, fmt.Sprintf("pages%d", time.Now().UnixNano()), "pages-password")
+	now := time.Now()
+
+	addTask := func(days int, title string) string {
+		return addTokenTask(t, token, map[string]any{
+			"date":  now.AddDate(0, 0, days).Format(`20060102`),
+			"title": title,
+		})
+	}
+
+	var ids []string
+	for i, days := range []int{1, 2, 2, 2, 3, 5, 8} {
+		ids = append(ids, addTask(days, fmt.Sprintf("Задача %d", i)))
+	}
+
+	page := getPage(t, token, url.Values{"limit": {"3"}})
+	assert.Equal(t, 7, page.Total)
+	assert.NotEmpty(t, page.NextCursor)
+
+	var got []string
+	for _, task := range page.Tasks {
+		got = append(got, task["id"])
+	}
+	// задача перед курсором не должна сдвигать следующие страницы
+	addTask(1, "Задача после первой страницы")
+	for page.NextCursor != "" {
+		page = getPage(t, token, url.Values{"limit": {"3"}, "cursor": {page.NextCursor}})
+		assert.Equal(t, 8, page.Total)
+		for _, task := range page.Tasks {
+			got = append(got, task["id"])
+		}
+	}
+	assert.Equal(t, ids, got)
+
+	page = getPage(t, token, url.Values{"search": {"задача"}, "limit": {"5"}})
+	assert.Equal(t, 8, page.Total)
+	assert.Len(t, page.Tasks, 5)
+	page = getPage(t, token, url.Values{"search": {"задача"}, "limit": {"5"}, "cursor": {page.NextCursor}})
+	assert.Len(t, page.Tasks, 3)
+	assert.Empty(t, page.NextCursor)
+
+	page = getPage(t, token, url.Values{"search": {"title:задача after:today"}, "limit": {"8"}})
+	assert.Equal(t, 8, page.Total)
+	assert.Empty(t, page.NextCursor)
+
+	for _, params := range []string{"limit=0", "limit=abc", "limit=100000", "cursor=abc", "cursor=MToxOjI"} {
+		code, _ := tokenJSON(t, "api/tasks?"+params, token, nil, http.MethodGet)
+		assert.Equal(t, http.StatusBadRequest, code, params)
+	}
+}
+
+func TestSearchRankPages(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "")
+	memory := httptest.NewServer(api.NewServer(db.NewMemoryStore()))
+	defer memory.Close()
+
+	token := signup(t, fmt.Sprintf("rank%d", time.Now().UnixNano()), "rank-password")
+	word := fmt.Sprintf("скворечник%d", time.Now().UnixNano())
+	now := time.Now()
+
+	stores := map[string]func(apipath string, values map[string]any, method string) (int, map[string]any){
+		"sql": func(apipath string, values map[string]any, method string) (int, map[string]any) {
+			code, body := tokenJSON(t, apipath, token, values, method)
+			var m map[string]any
+			assert.NoError(t, json.Unmarshal(body, &m), string(body))
+			return code, m
+		},
+		"memory": func(apipath string, values map[string]any, method string) (int, map[string]any) {
+			return serverJSON(t, memory, apipath, values, method)
+		},
+	}
+	for name, call := range stores {
+		add := func(days int, title, comment string) string {
+			code, m := call("api/task", map[string]any{
+				"date":    now.AddDate(0, 0, days).Format(`20060102`),
+				"title":   title,
+				"comment": comment,
+			}, http.MethodPost)
+			assert.Equal(t, http.StatusCreated, code, m)
+			return fmt.Sprint(m["id"])
+		}
+		// совпадения только в комментарии идут раньше по дате, но ниже по релевантности
+		var weak []string
+		for i := 1; i <= 3; i++ {
+			weak = append(weak, add(i, fmt.Sprintf("Задача %d", i), "проверить "+word))
+		}
+		medium := add(10, "Купить "+word, "до выходных")
+		best := add(20, "Повесить "+word, "собрать "+word)
+
+		var got []string
+		params := url.Values{"search": {word}, "limit": {"2"}}
+		for {
+			code, m := call("api/tasks?"+params.Encode(), nil, http.MethodGet)
+			assert.Equal(t, http.StatusOK, code, m)
+			assert.Equal(t, float64(5), m["total"], name)
+			for _, task := range m["tasks"].([]any) {
+				got = append(got, fmt.Sprint(task.(map[string]any)["id"]))
+			}
+			cursor, _ := m["next_cursor"].(string)
+			if cursor == "" {
+				break
+			}
+			params.Set("cursor", cursor)
+		}
+		assert.Equal(t, append([]string{best, medium}, weak...), got, name)
+	}
+}
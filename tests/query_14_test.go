@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchQuery(t *testing.T) {
+	token := signup(t, fmt.Sprintf("query%d", time.Now().UnixNano()), "query-password")
+	now := time.Now()
+	day := func(n int) string { return now.AddDate(0, 0, n).Format(`20060102`) }
+
+	for _, v := range []map[string]any{
+		{"date": day(1), "title": "Weekly report", "comment": "final", "repeat": "w 1,5"},
+		{"date": day(3), "title": "Monthly report", "comment": "draft", "repeat": "m 1"},
+		{"date": day(5), "title": "Report for board", "comment": "draft version"},
+		{"date": day(7), "title": "Buy milk", "comment": "and bread", "repeat": "d 7"},
+	} {
+		code, body := tokenJSON(t, "api/task", token, v, http.MethodPost)
+		assert.Equal(t, http.StatusCreated, code, string(body))
+	}
+
+	titles := func(search string) []string {
+		var list []string
+		for _, task := range searchTasks(t, token, search) {
+			list = append(list, task["title"])
+		}
+		return list
+	}
+
+	assert.Equal(t, []string{"Weekly report", "Monthly report", "Report for board"}, titles("title:report"))
+	assert.Equal(t, []string{"Weekly report"}, titles("title:report repeat:w"))
+	assert.Equal(t, []string{"Weekly report", "Monthly report"}, titles("title:report is:recurring"))
+	assert.Equal(t, []string{"Weekly report"}, titles("title:report -comment:draft"))
+	assert.Equal(t, []string{"Weekly report", "Buy milk"}, titles("repeat:w OR repeat:d"))
+	assert.Equal(t, []string{"Report for board"}, titles("report NOT (repeat:w OR repeat:m)"))
+	assert.Equal(t, []string{"Monthly report", "Report for board"},
+		titles("after:today before:"+now.AddDate(0, 0, 6).Format("02.01.2006")+" title:report -repeat:w"))
+	assert.Equal(t, []string{"Report for board"}, titles(`comment:"draft version"`))
+	assert.Equal(t, []string{"Buy milk"}, titles("on:"+day(7)))
+	assert.Empty(t, titles("is:overdue"))
+
+	for search, pos := range map[string]int{
+		"title:report colour:red": 14,
+		"before:someday":          1,
+		"repeat:x":                1,
+		"report OR":               8,
+		"(report":                 1,
+		"report)":                 7,
+		`title:"draft`:            7,
+		"report AND AND draft":    8,
+	} {
+		code, body := tokenJSON(t, "api/tasks?search="+url.QueryEscape(search), token, nil, http.MethodGet)
+		assert.Equal(t, http.StatusBadRequest, code, search)
+
+		var m map[string]string
+		assert.NoError(t, json.Unmarshal(body, &m))
+		assert.Contains(t, m["error"], fmt.Sprintf("position %d ", pos), search)
+	}
+}
@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/eOne007/final-project-yapr/internal/repeater"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAfterMatchesSequence сверяет прямое вычисление первой даты после now
+// с последовательным вычислением дат от начальной, как при отметках о выполнении
+func TestAfterMatchesSequence(t *testing.T) {
+	rules := []string{"d 1", "d 7", "d 400", "w 1", "w 3,7", "w 1,2,3,4,5,6,7",
+		"w 1,4 /2", "w 7 /3", "w 2,6 /52",
+		"m 1", "m 31", "m -1", "m -2,15", "m 29 2", "m 31 12", "m 30 1,4,6",
+		"m 2tue", "m -1fri 3,6,9,12", "m 5sun", "m -5mon 2", "m 1,1mon,-1sun", "y",
+		"FREQ=DAILY;INTERVAL=3;BYDAY=MO,FR", "FREQ=WEEKLY;INTERVAL=3;WKST=SU;BYDAY=SU,WE",
+		"FREQ=MONTHLY;INTERVAL=5;BYDAY=-1SU,2MO", "FREQ=MONTHLY;BYMONTHDAY=13;BYDAY=FR",
+		"FREQ=YEARLY;INTERVAL=2;BYMONTH=2;BYMONTHDAY=29", "FREQ=YEARLY;BYDAY=-1WE", "FREQ=MONTHLY;INTERVAL=7"}
+	rnd := rand.New(rand.NewSource(1))
+	base := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for _, repeat := range rules {
+		rule, err := repeater.Parse(repeat)
+		assert.NoError(t, err)
+		for i := 0; i < 200; i++ {
+			start := base.AddDate(0, 0, rnd.Intn(365*30))
+			now := base.AddDate(0, 0, rnd.Intn(365*30))
+
+			want, ok := rule.Next(start)
+			for ok && !repeater.AfterNow(want, now) {
+				want, ok = rule.Next(want)
+			}
+			got, gotOK := repeater.After(rule, start, now)
+			assert.Equal(t, ok, gotOK)
+			assert.Equal(t, want.Format("20060102"), got.Format("20060102"),
+				"%s start %s now %s", repeat, start.Format("20060102"), now.Format("20060102"))
+		}
+	}
+}
+
+// TestAfterCenturies проверяет прямое вычисление, когда между start и now больше 292 лет,
+// на которых переполняется time.Duration
+func TestAfterCenturies(t *testing.T) {
+	rules := []string{"d 1", "d 7", "w 3,7", "w 1,4 /2",
+		"FREQ=DAILY;INTERVAL=3", "FREQ=WEEKLY;INTERVAL=3;BYDAY=SU,WE"}
+	tbl := []struct {
+		start string
+		now   string
+	}{
+		{"17000101", "20261017"},
+		{"16010315", "20261017"},
+		{"20240101", "99991231"},
+	}
+	for _, repeat := range rules {
+		rule, err := repeater.Parse(repeat)
+		assert.NoError(t, err)
+		for _, v := range tbl {
+			start, err := time.Parse("20060102", v.start)
+			assert.NoError(t, err)
+			now, err := time.Parse("20060102", v.now)
+			assert.NoError(t, err)
+
+			want, ok := rule.Next(start)
+			for ok && !repeater.AfterNow(want, now) {
+				want, ok = rule.Next(want)
+			}
+			got, gotOK := repeater.After(rule, start, now)
+			assert.Equal(t, ok, gotOK)
+			assert.True(t, repeater.AfterNow(got, now), "%s start %s now %s", repeat, v.start, v.now)
+			assert.Equal(t, want.Format("20060102"), got.Format("20060102"),
+				"%s start %s now %s", repeat, v.start, v.now)
+		}
+	}
+}
+
+func TestImpossibleRules(t *testing.T) {
+	for _, repeat := range []string{"m 31 2", "m 30 2", "m 30,31 2", "m 31 4,6,9,11"} {
+		_, err := repeater.Parse(repeat)
+		assert.Error(t, err, repeat)
+	}
+	for _, repeat := range []string{"m 29 2", "m 31 2,3", "m -1 2"} {
+		_, err := repeater.Parse(repeat)
+		assert.NoError(t, err, repeat)
+	}
+}
+
+// benchmarkNextDate измеряет вычисление следующей даты для задачи, созданной много лет назад
+func benchmarkNextDate(b *testing.B, dstart, repeat string) {
+	now := time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC)
+	for b.Loop() {
+		if _, err := repeater.NextDate(now, dstart, repeat); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNextDateDaily(b *testing.B) {
+	benchmarkNextDate(b, "19700101", "d 1")
+}
+
+func BenchmarkNextDateWeekly(b *testing.B) {
+	benchmarkNextDate(b, "19700101", "w 3,7")
+}
+
+func BenchmarkNextDateMonthly(b *testing.B) {
+	benchmarkNextDate(b, "19700101", "m 31 12")
+}
+
+func BenchmarkNextDateMonthlyLeap(b *testing.B) {
+	benchmarkNextDate(b, "19700101", "m 29 2")
+}
+
+func BenchmarkNextDateYearly(b *testing.B) {
+	benchmarkNextDate(b, "17000101", "y")
+}
@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/eOne007/final-project-yapr/internal/repeater"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRRule(t *testing.T) {
+	date := func(s string) time.Time {
+		d, err := time.Parse("20060102", s)
+		assert.NoError(t, err)
+		return d
+	}
+	format := func(dates []time.Time) []string {
+		res := make([]string, 0, len(dates))
+		for _, d := range dates {
+			res = append(res, d.Format("20060102"))
+		}
+		return res
+	}
+
+	// примеры из RFC 5545, дата задачи соответствует DTSTART и сама в список не входит
+	for _, v := range []struct {
+		start, repeat string
+		n             int
+		want          []string
+	}{
+		{"19970902", "RRULE:FREQ=DAILY;COUNT=4", 5, []string{"19970903", "19970904", "19970905"}},
+		{"19971222", "FREQ=DAILY;UNTIL=19971224", 5, []string{"19971223", "19971224"}},
+		{"19970902", "FREQ=WEEKLY;INTERVAL=2;WKST=SU;BYDAY=TU,TH", 5,
+			[]string{"19970904", "19970916", "19970918", "19970930", "19971002"}},
+		{"19970905", "FREQ=MONTHLY;BYDAY=1FR", 3, []string{"19971003", "19971107", "19971205"}},
+		{"19970922", "FREQ=MONTHLY;BYDAY=-2MO", 3, []string{"19971020", "19971117", "19971222"}},
+		{"19970928", "FREQ=MONTHLY;BYMONTHDAY=-3", 3, []string{"19971029", "19971128", "19971229"}},
+		{"19970907", "FREQ=MONTHLY;INTERVAL=2;BYDAY=1SU,-1SU", 4,
+			[]string{"19970928", "19971102", "19971130", "19980104"}},
+		{"19970519", "FREQ=YEARLY;BYDAY=20MO", 2, []string{"19980518", "19990517"}},
+		{"19970313", "FREQ=YEARLY;BYMONTH=3;BYDAY=TH", 4, []string{"19970320", "19970327", "19980305", "19980312"}},
+		{"19970902", "FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13", 3, []string{"19980213", "19980313", "19981113"}},
+		{"20240229", "FREQ=YEARLY", 2, []string{"20280229", "20320229"}},
+		{"20240131", "FREQ=MONTHLY", 3, []string{"20240331", "20240531", "20240731"}},
+		{"20240126", "freq=weekly", 2, []string{"20240202", "20240209"}},
+	} {
+		start := date(v.start)
+		dates, err := repeater.Occurrences(start, v.repeat, start, v.n)
+		assert.NoError(t, err, v.repeat)
+		assert.Equal(t, v.want, format(dates), v.repeat)
+	}
+
+	for _, v := range []struct {
+		repeat string
+		pos    int
+		token  string
+	}{
+		{"FREQ=HOURLY", 6, "HOURLY"},
+		{"FREQ=DAILY;BYDAY=MO,2MO", 21, "2MO"},
+		{"FREQ=MONTHLY;BYDAY=6FR", 20, "6FR"},
+		{"RRULE:FREQ=DAILY;INTERVAL=0", 27, "0"},
+		{"FREQ=DAILY;BYSETPOS=1", 12, "BYSETPOS"},
+		{"FREQ=DAILY;FREQ=WEEKLY", 12, "FREQ=WEEKLY"},
+		{"FREQ=DAILY;BYMONTH=13", 20, "13"},
+		{"INTERVAL=2;FREQ=", 12, "FREQ="},
+		{"FREQ=WEEKLY;COUNT=2;UNTIL=20250101", 1, "FREQ=WEEKLY;COUNT=2;UNTIL=20250101"},
+		{"FREQ=MONTHLY;BYMONTHDAY=30;BYMONTH=2", 1, "FREQ=MONTHLY;BYMONTHDAY=30;BYMONTH=2"},
+	} {
+		_, err := repeater.Parse(v.repeat)
+		var parseErr *repeater.ParseError
+		if assert.True(t, errors.As(err, &parseErr), "%q: %v", v.repeat, err) {
+			assert.Equal(t, v.pos, parseErr.Pos, v.repeat)
+			assert.Equal(t, v.token, parseErr.Token, v.repeat)
+		}
+	}
+
+	// правила, переведенные в RRULE, дают те же даты, что и исходные
+	for _, v := range []struct{ repeat, rrule string }{
+		{"d 3", "FREQ=DAILY;INTERVAL=3"},
+		{"w 4,1 /2", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH"},
+		{"w 7", "FREQ=WEEKLY;BYDAY=SU"},
+		{"m 1,-1 3,9", "FREQ=MONTHLY;BYMONTHDAY=-1,1;BYMONTH=3,9"},
+		{"m 2tue,-1fri", "FREQ=MONTHLY;BYDAY=-1FR,2TU"},
+		{"m 31", "FREQ=MONTHLY;BYMONTHDAY=31"},
+		{"y", "FREQ=YEARLY"},
+	} {
+		rule, err := repeater.Parse(v.repeat)
+		assert.NoError(t, err)
+		rrule, err := repeater.ToRRule(rule)
+		assert.NoError(t, err)
+		assert.Equal(t, v.rrule, rrule, v.repeat)
+		for _, start := range []string{"20240101", "20240131", "20240315", "20250228"} {
+			want, err := repeater.Occurrences(date(start), v.repeat, date("20240201"), 20)
+			assert.NoError(t, err)
+			got, err := repeater.Occurrences(date(start), rrule, date("20240201"), 20)
+			assert.NoError(t, err)
+			assert.Equal(t, format(want), format(got), "%s start %s", v.repeat, start)
+		}
+	}
+	rule, err := repeater.Parse("m 1,2tue")
+	assert.NoError(t, err)
+	_, err = repeater.ToRRule(rule)
+	assert.Error(t, err)
+}
+
+func TestRRuleAPI(t *testing.T) {
+	token := signup(t, fmt.Sprintf("rrule%d", time.Now().UnixNano()), "rrule-password")
+
+	code, body := tokenJSON(t, "api/nextdate?now=20240126&date=20240101&repeat="+
+		url.QueryEscape("FREQ=MONTHLY;BYDAY=-1FR"), token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code, string(body))
+	assert.Equal(t, "20240223", string(body))
+
+	code, body = tokenJSON(t, "api/rrule?repeat="+url.QueryEscape("w 1,4 /2"), token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code, string(body))
+	assert.JSONEq(t, `{"rrule": "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH"}`, string(body))
+	code, _ = tokenJSON(t, "api/rrule?repeat="+url.QueryEscape("m 1,2tue"), token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// COUNT и UNTIL сохраняются в условиях окончания задачи
+	day := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+	code, body = tokenJSON(t, "api/task", token, map[string]any{
+		"date": day, "title": "Планерка", "repeat": "RRULE:FREQ=WEEKLY;BYDAY=MO;COUNT=3",
+	}, http.MethodPost)
+	assert.Equal(t, http.StatusCreated, code, string(body))
+	var created map[string]any
+	assert.NoError(t, json.Unmarshal(body, &created))
+	id, _ := created["id"].(string)
+
+	code, body = tokenJSON(t, "api/task?id="+id, token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code, string(body))
+	var task map[string]any
+	assert.NoError(t, json.Unmarshal(body, &task))
+	assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO", task["repeat"])
+	assert.Equal(t, float64(3), task["remaining"])
+
+	// даты серии до сегодняшней входят в COUNT: из трех еженедельных дат, начатых 13 дней назад,
+	// остается одна - завтрашняя, после ее выполнения серия заканчивается
+	code, body = tokenJSON(t, "api/task", token, map[string]any{
+		"date":  time.Now().AddDate(0, 0, -13).Format(`20060102`),
+		"title": "Прошлая серия", "repeat": "FREQ=WEEKLY;COUNT=3",
+	}, http.MethodPost)
+	assert.Equal(t, http.StatusCreated, code, string(body))
+	assert.NoError(t, json.Unmarshal(body, &created))
+	id, _ = created["id"].(string)
+	code, body = tokenJSON(t, "api/task?id="+id, token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code, string(body))
+	task = nil
+	assert.NoError(t, json.Unmarshal(body, &task))
+	assert.Equal(t, day, task["date"])
+	assert.Equal(t, float64(1), task["remaining"])
+	code, _ = tokenJSON(t, "api/task/done?id="+id, token, nil, http.MethodPost)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = tokenJSON(t, "api/task?id="+id, token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, body = tokenJSON(t, "api/task", token, map[string]any{
+		"date": day, "title": "Неверное правило", "repeat": "FREQ=DAILY;BYDAY=1MO",
+	}, http.MethodPost)
+	assert.Equal(t, http.StatusBadRequest, code, string(body))
+}
@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/eOne007/final-project-yapr/internal/repeater"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRule(t *testing.T) {
+	for _, v := range []struct {
+		repeat, canonical string
+		rule              repeater.Rule
+	}{
+		{"d 7", "d 7", repeater.Daily{Interval: 7}},
+		{" d   400 ", "d 400", repeater.Daily{Interval: 400}},
+		{"w 5,1,3,1", "w 1,3,5", repeater.Weekly{Weekdays: []int{1, 3, 5}, Interval: 1}},
+		{"w 1, 3", "w 1,3", repeater.Weekly{Weekdays: []int{1, 3}, Interval: 1}},
+		{"w 4,1 /2", "w 1,4 /2", repeater.Weekly{Weekdays: []int{1, 4}, Interval: 2}},
+		{"w 5/03", "w 5 /3", repeater.Weekly{Weekdays: []int{5}, Interval: 3}},
+		{"w 2 /1", "w 2", repeater.Weekly{Weekdays: []int{2}, Interval: 1}},
+		{"m 07,19 05,6", "m 7,19 5,6", repeater.Monthly{Days: []int{7, 19}, Months: []int{5, 6}}},
+		{"m -1,15,-2", "m -2,-1,15", repeater.Monthly{Days: []int{-2, -1, 15}}},
+		{"m -1fri, 2TUE,15 3,6", "m 15,-1fri,2tue 3,6", repeater.Monthly{Days: []int{15},
+			Weekdays: []repeater.NthWeekday{{N: -1, Weekday: 5}, {N: 2, Weekday: 2}}, Months: []int{3, 6}}},
+		{"y", "y", repeater.Yearly{}},
+	} {
+		rule, err := repeater.Parse(v.repeat)
+		assert.NoError(t, err, v.repeat)
+		assert.Equal(t, v.rule, rule, v.repeat)
+		if rule == nil {
+			continue
+		}
+		assert.Equal(t, v.canonical, rule.String(), v.repeat)
+
+		again, err := repeater.Parse(rule.String())
+		assert.NoError(t, err)
+		assert.Equal(t, rule, again)
+	}
+
+	for _, v := range []struct {
+		repeat string
+		pos    int
+		token  string
+	}{
+		{"", 1, ""},
+		{"k 34", 1, "k"},
+		{"d", 2, "d"},
+		{"d 401", 3, "401"},
+		{"d 7 1", 5, "1"},
+		{"w 1,8,3", 5, "8"},
+		{"w 1 2", 5, "2"},
+		{"w 1 /53", 6, "53"},
+		{"w 1/0", 5, "0"},
+		{"w /2", 3, "/2"},
+		{"w 1 /2 3", 8, "3"},
+		{"m 1,40 2", 5, "40"},
+		{"m 1 2,13", 7, "13"},
+		{"m -3", 3, "-3"},
+		{"m 1,6tue", 5, "6tue"},
+		{"m 2fri,3xyz", 8, "3xyz"},
+		{"y 1", 3, "1"},
+	} {
+		_, err := repeater.Parse(v.repeat)
+		var parseErr *repeater.ParseError
+		if assert.True(t, errors.As(err, &parseErr), "%q: %v", v.repeat, err) {
+			assert.Equal(t, v.pos, parseErr.Pos, v.repeat)
+			assert.Equal(t, v.token, parseErr.Token, v.repeat)
+		}
+	}
+
+	date := func(s string) time.Time {
+		d, err := time.Parse("20060102", s)
+		assert.NoError(t, err)
+		return d
+	}
+	for _, v := range []struct{ repeat, after, want string }{
+		{"d 3", "20240130", "20240202"},
+		{"w 1,5", "20240126", "20240129"},
+		{"m -1", "20240131", "20240229"},
+		{"m 30 2,4", "20240101", "20240430"},
+		{"y", "20240229", "20250301"},
+	} {
+		rule, err := repeater.Parse(v.repeat)
+		assert.NoError(t, err)
+		next, ok := rule.Next(date(v.after))
+		assert.True(t, ok)
+		assert.Equal(t, v.want, next.Format("20060102"), v.repeat)
+	}
+}
+
+func TestRuleAfterFutureStart(t *testing.T) {
+	// now = 26.01.2024, начальная дата в будущем подходит под правило, но следующая дата все равно позже нее
+	now := time.Date(2024, 1, 26, 0, 0, 0, 0, time.UTC)
+	for _, v := range []struct {
+		date, repeat, want string
+	}{
+		{"20240129", "w 1", "20240205"},
+		{"20240129", "w 1,3", "20240131"},
+		{"20240201", "w 4 /2", "20240215"},
+		{"20240215", "m 15", "20240315"},
+		{"20240229", "m -1,1", "20240301"},
+		{"20240212", "m 2mon", "20240311"},
+		// не подходящая под правило начальная дата переносится на ближайшую подходящую
+		{"20240130", "w 1", "20240205"},
+		{"20240216", "m 15", "20240315"},
+	} {
+		next, err := repeater.NextDate(now, v.date, v.repeat)
+		assert.NoError(t, err)
+		assert.Equal(t, v.want, next, "%s %s", v.date, v.repeat)
+	}
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func searchTasks(t *testing.T, token, search string) []map[string]string {
+	code, body := tokenJSON(t, "api/tasks?search="+url.QueryEscape(search), token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code, string(body))
+
+	var m struct {
+		Tasks []map[string]string `json:"tasks"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return m.Tasks
+}
+
+func TestFullTextSearch(t *testing.T) {
+	token := signup(t, fmt.Sprintf("search%d", time.Now().UnixNano()), "search-password")
+	date := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+
+	for _, v := range []map[string]any{
+		{"date": date, "title": "Подготовить данные", "comment": "для квартального отчёта"},
+		{"date": time.Now().AddDate(0, 0, 2).Format(`20060102`), "title": "Квартальный ОТЧЁТ", "comment": "отправить <директору>"},
+		{"date": date, "title": "Report draft", "comment": "Quarterly numbers"},
+	} {
+		code, body := tokenJSON(t, "api/task", token, v, http.MethodPost)
+		assert.Equal(t, http.StatusCreated, code, string(body))
+	}
+
+	tasks := searchTasks(t, token, "квартальн")
+	assert.Len(t, tasks, 2)
+	if len(tasks) == 2 {
+		assert.Equal(t, "Квартальный ОТЧЁТ", tasks[0]["title"], "совпадение в заголовке должно быть выше")
+		assert.Contains(t, tasks[0]["snippet"], "<mark>Квартальный</mark>")
+	}
+
+	tasks = searchTasks(t, token, "отчёт")
+	assert.Len(t, tasks, 2)
+	tasks = searchTasks(t, token, `"отчёт"`)
+	assert.Len(t, tasks, 1)
+
+	tasks = searchTasks(t, token, `"квартальный отчёт"`)
+	assert.Len(t, tasks, 1)
+	tasks = searchTasks(t, token, `"отчёт квартальный"`)
+	assert.Empty(t, tasks)
+
+	tasks = searchTasks(t, token, "директору")
+	assert.Len(t, tasks, 1)
+	if len(tasks) == 1 {
+		assert.Contains(t, tasks[0]["snippet"], "&lt;<mark>директору</mark>&gt;")
+	}
+
+	tasks = searchTasks(t, token, "QUARTER report")
+	assert.Len(t, tasks, 1)
+	tasks = searchTasks(t, token, "quarter отчёт")
+	assert.Empty(t, tasks)
+	tasks = searchTasks(t, token, `"*" OR -`)
+	assert.Empty(t, tasks)
+}
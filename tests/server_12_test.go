@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/eOne007/final-project-yapr/pkg/api"
+	"github.com/eOne007/final-project-yapr/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func serverJSON(t *testing.T, srv *httptest.Server, apipath string, values map[string]any, method string,
+	token ...string) (int, map[string]any) {
+	var data []byte
+	if len(values) > 0 {
+		var err error
+		data, err = json.Marshal(values)
+		assert.NoError(t, err)
+	}
+	req, err := http.NewRequest(method, srv.URL+"/"+apipath, bytes.NewBuffer(data))
+	assert.NoError(t, err)
+	if len(token) > 0 && len(token[0]) > 0 {
+		req.Header.Set("Authorization", "Bearer "+token[0])
+	}
+	resp, err := srv.Client().Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m), string(body))
+	return resp.StatusCode, m
+}
+
+func TestMemoryServer(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "")
+
+	first := httptest.NewServer(api.NewServer(db.NewMemoryStore()))
+	defer first.Close()
+	second := httptest.NewServer(api.NewServer(db.NewMemoryStore()))
+	defer second.Close()
+
+	now := time.Now()
+	code, m := serverJSON(t, first, "api/task", map[string]any{
+		"date":    now.Format(`20060102`),
+		"title":   "Проверить хранилище в памяти",
+		"comment": "без файла БД",
+		"repeat":  "d 2",
+	}, http.MethodPost)
+	assert.Equal(t, http.StatusCreated, code)
+	id := fmt.Sprint(m["id"])
+
+	code, m = serverJSON(t, first, "api/task?id="+id, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Проверить хранилище в памяти", m["title"])
+
+	code, _ = serverJSON(t, second, "api/task?id="+id, nil, http.MethodGet)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, m = serverJSON(t, first, "api/tasks?search=ФАЙЛА", nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, m["tasks"], 1)
+	_, m = serverJSON(t, first, "api/tasks?search=repeat:d+-title:хранилище", nil, http.MethodGet)
+	assert.Empty(t, m["tasks"])
+	_, m = serverJSON(t, first, "api/tasks?search=repeat:d+OR+repeat:w", nil, http.MethodGet)
+	assert.Len(t, m["tasks"], 1)
+
+	serverJSON(t, first, "api/task", map[string]any{"title": "Вторая задача"}, http.MethodPost)
+	_, m = serverJSON(t, first, "api/tasks?limit=1", nil, http.MethodGet)
+	assert.Len(t, m["tasks"], 1)
+	assert.Equal(t, float64(2), m["total"])
+	_, m = serverJSON(t, first, "api/tasks?limit=1&cursor="+fmt.Sprint(m["next_cursor"]), nil, http.MethodGet)
+	assert.Len(t, m["tasks"], 1)
+	assert.Equal(t, "", m["next_cursor"])
+
+	code, _ = serverJSON(t, first, "api/task/done?id="+id, nil, http.MethodPost)
+	assert.Equal(t, http.StatusOK, code)
+	_, m = serverJSON(t, first, "api/task?id="+id, nil, http.MethodGet)
+	assert.Equal(t, now.AddDate(0, 0, 2).Format(`20060102`), m["date"])
+
+	code, _ = serverJSON(t, first, "api/task?id="+id, nil, http.MethodDelete)
+	assert.Equal(t, http.StatusOK, code)
+	code, m = serverJSON(t, first, "api/tasks", nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, m["tasks"], 1)
+}
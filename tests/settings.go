@@ -2,6 +2,8 @@ package tests
 
 var Port = 7540
 var DBFile = "../scheduler.db"
+var DBDriver = "sqlite"
+var DBDSN = ``
 var FullNextDate = true
 var Search = true
 var Token = ``
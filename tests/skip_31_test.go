@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkipSnooze(t *testing.T) {
+	token := signup(t, fmt.Sprintf("skip%d", time.Now().UnixNano()), "skip-password")
+	now := time.Now()
+	day := func(n int) string { return now.AddDate(0, 0, n).Format(`20060102`) }
+
+	action := func(apipath string) string {
+		code, m := tokenMap(t, apipath, token, nil, http.MethodPost)
+		assert.Equal(t, http.StatusOK, code, m)
+		undo, _ := m["undo_token"].(string)
+		assert.NotEmpty(t, undo)
+		return undo
+	}
+	// history возвращает отметки журнала по задаче в виде "вид дата->следующая дата"
+	history := func(id string) []string {
+		_, m := tokenMap(t, "api/task/history?id="+id, token, nil, http.MethodGet)
+		var list []string
+		for _, v := range m["completions"].([]any) {
+			c := v.(map[string]any)
+			list = append(list, fmt.Sprintf("%v %v->%v", c["kind"], c["date"], c["next_date"]))
+		}
+		return list
+	}
+	agenda := func(id string) []string {
+		_, m := tokenMap(t, fmt.Sprintf("api/agenda?from=%s&to=%s", day(0), day(20)), token, nil, http.MethodGet)
+		var dates []string
+		for _, v := range m["days"].([]any) {
+			d := v.(map[string]any)
+			for _, task := range d["tasks"].([]any) {
+				if task.(map[string]any)["id"] == id {
+					dates = append(dates, fmt.Sprint(d["date"]))
+				}
+			}
+		}
+		return dates
+	}
+
+	id := addTokenTask(t, token, map[string]any{"date": day(0), "title": "Уборка", "repeat": "d 7"})
+
+	// пропуск переносит задачу на следующую дату и записывается в журнал как пропуск
+	action("api/task/skip?id=" + id)
+	assert.Equal(t, day(7), getTokenTask(t, token, id)["date"])
+	assert.Equal(t, []string{"skipped " + day(0) + "->" + day(7)}, history(id))
+
+	// перенос меняет дату только текущего повторения, следующие считаются от исходной даты
+	action("api/task/snooze?id=" + id + "&until=" + day(9))
+	task := getTokenTask(t, token, id)
+	assert.Equal(t, day(9), task["date"])
+	assert.Equal(t, day(7), task["snoozed_from"])
+	assert.Equal(t, []string{day(9), day(14)}, agenda(id))
+
+	// повторный перенос сохраняет исходную дату
+	undo := action("api/task/snooze?id=" + id + "&until=" + day(10))
+	task = getTokenTask(t, token, id)
+	assert.Equal(t, day(10), task["date"])
+	assert.Equal(t, day(7), task["snoozed_from"])
+
+	// отмена возвращает предыдущий перенос и удаляет отметку из журнала
+	code, _ := tokenMap(t, "api/undo?token="+undo, token, nil, http.MethodPost)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, day(9), getTokenTask(t, token, id)["date"])
+	assert.Equal(t, []string{
+		"skipped " + day(0) + "->" + day(7),
+		"snoozed " + day(7) + "->" + day(9),
+	}, history(id))
+
+	// изменение задачи без snoozed_from не отрывает перенесенное повторение от серии
+	code, _ = tokenMap(t, "api/task", token, map[string]any{"id": id, "date": day(9), "title": "Уборка дома",
+		"comment": "", "repeat": "d 7"}, http.MethodPut)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, day(7), getTokenTask(t, token, id)["snoozed_from"])
+	assert.Equal(t, []string{day(9), day(14)}, agenda(id))
+
+	// выполнение перенесенного повторения возвращает задачу в серию
+	action("api/task/done?id=" + id)
+	task = getTokenTask(t, token, id)
+	assert.Equal(t, day(14), task["date"])
+	assert.NotContains(t, task, "snoozed_from")
+	assert.Equal(t, []string{
+		"skipped " + day(0) + "->" + day(7),
+		"snoozed " + day(7) + "->" + day(9),
+		"done " + day(9) + "->" + day(14),
+	}, history(id))
+
+	// пропуск последнего повторения завершает серию
+	last := addTokenTask(t, token, map[string]any{"date": day(1), "title": "Последний раз", "repeat": "d 1", "remaining": 1})
+	action("api/task/skip?id=" + last)
+	code, _ = tokenMap(t, "api/task?id="+last, token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, []string{"skipped " + day(1) + "-><nil>"}, history(last))
+
+	// разовую задачу можно перенести, но не пропустить
+	once := addTokenTask(t, token, map[string]any{"date": day(1), "title": "Разовая"})
+	action("api/task/snooze?id=" + once + "&until=" + day(3))
+	task = getTokenTask(t, token, once)
+	assert.Equal(t, day(3), task["date"])
+	assert.NotContains(t, task, "snoozed_from")
+	code, _ = tokenMap(t, "api/task/skip?id="+once, token, nil, http.MethodPost)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	for _, query := range []string{
+		"id=" + once,
+		"id=" + once + "&until=yesterday",
+		"id=" + once + "&until=" + day(3),
+		"id=" + once + "&until=someday",
+		"until=" + day(3),
+	} {
+		code, _ = tokenMap(t, "api/task/snooze?"+query, token, nil, http.MethodPost)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+	}
+	code, _ = tokenMap(t, "api/task/snooze?id=999999999&until="+day(3), token, nil, http.MethodPost)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = tokenMap(t, "api/task/skip?id=999999999", token, nil, http.MethodPost)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = tokenMap(t, "api/task/skip?id="+id, token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+	code, _ = tokenMap(t, "api/task/snooze?id="+id+"&until="+day(3), token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+}
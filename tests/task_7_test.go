@@ -31,7 +31,8 @@ func TestDone(t *testing.T) {
 
 	ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
 	assert.NoError(t, err)
-	assert.Empty(t, ret)
+	assert.NotContains(t, ret, "error")
+	assert.NotEmpty(t, ret["undo_token"])
 	notFoundTask(t, id)
 
 	id = addTask(t, task{
@@ -42,10 +43,11 @@ func TestDone(t *testing.T) {
 	for i := 0; i < 3; i++ {
 		ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
 		assert.NoError(t, err)
-		assert.Empty(t, ret)
+		assert.NotContains(t, ret, "error")
+		assert.NotEmpty(t, ret["undo_token"])
 
 		var task Task
-		err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+		err = db.Get(&task, db.Rebind(`SELECT * FROM scheduler WHERE id=?`), id)
 		assert.NoError(t, err)
 		now = now.AddDate(0, 0, 3)
 		assert.Equal(t, now.Format(`20060102`), task.Date)
@@ -62,7 +64,8 @@ func TestDelTask(t *testing.T) {
 	})
 	ret, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
 	assert.NoError(t, err)
-	assert.Empty(t, ret)
+	assert.NotContains(t, ret, "error")
+	assert.NotEmpty(t, ret["undo_token"])
 
 	notFoundTask(t, id)
 
@@ -32,10 +32,12 @@ func getTasks(t *testing.T, search string) []map[string]string {
 	body, err := requestJSON(url, nil, http.MethodGet)
 	assert.NoError(t, err)
 
-	var m map[string][]map[string]string
+	var m struct {
+		Tasks []map[string]string `json:"tasks"`
+	}
 	err = json.Unmarshal(body, &m)
 	assert.NoError(t, err)
-	return m["tasks"]
+	return m.Tasks
 }
 
 func TestTasks(t *testing.T) {
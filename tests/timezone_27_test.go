@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/eOne007/final-project-yapr/internal/repeater"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToday(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	assert.NoError(t, err)
+	newYork, err := time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+
+	// 22:30 UTC - в Москве уже следующий день, в Нью-Йорке еще тот же
+	now := time.Date(2024, 3, 10, 22, 30, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), repeater.Today(now, moscow))
+	assert.Equal(t, time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), repeater.Today(now, newYork))
+
+	next, err := repeater.NextDate(repeater.Today(now, moscow), "20240301", "d 1")
+	assert.NoError(t, err)
+	assert.Equal(t, "20240312", next)
+	next, err = repeater.NextDate(repeater.Today(now, newYork), "20240301", "d 1")
+	assert.NoError(t, err)
+	assert.Equal(t, "20240311", next)
+}
+
+func TestTaskTimezone(t *testing.T) {
+	token := signup(t, fmt.Sprintf("timezone%d", time.Now().UnixNano()), "timezone-password")
+	// между UTC+14 и UTC-11 больше суток, поэтому даты в этих часовых поясах всегда различаются
+	east, err := time.LoadLocation("Pacific/Kiritimati")
+	assert.NoError(t, err)
+	west, err := time.LoadLocation("Pacific/Pago_Pago")
+	assert.NoError(t, err)
+	today := func(loc *time.Location, days int) string {
+		return time.Now().In(loc).AddDate(0, 0, days).Format(`20060102`)
+	}
+
+	code, body := tokenJSON(t, "api/settings", token, map[string]any{"timezone": "Mars/Olympus"}, http.MethodPut)
+	assert.Equal(t, http.StatusBadRequest, code, string(body))
+	code, body = tokenJSON(t, "api/settings", token, map[string]any{"timezone": "Pacific/Kiritimati"}, http.MethodPut)
+	assert.Equal(t, http.StatusOK, code, string(body))
+	code, body = tokenJSON(t, "api/settings", token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code, string(body))
+	var settings map[string]any
+	assert.NoError(t, json.Unmarshal(body, &settings))
+	assert.Equal(t, "Pacific/Kiritimati", settings["timezone"])
+
+	// без даты задача получает сегодняшнюю дату в часовом поясе пользователя или задачи
+	id := addTokenTask(t, token, map[string]any{"title": "По времени пользователя"})
+	assert.Equal(t, today(east, 0), getTokenTask(t, token, id)["date"])
+	id = addTokenTask(t, token, map[string]any{"title": "По времени задачи", "timezone": "Pacific/Pago_Pago"})
+	assert.Equal(t, today(west, 0), getTokenTask(t, token, id)["date"])
+
+	// время начала и длительность
+	id = addTokenTask(t, token, map[string]any{"title": "Созвон", "date": today(east, 1), "time": "9:30", "duration": 45})
+	task := getTokenTask(t, token, id)
+	assert.Equal(t, "09:30", task["time"])
+	assert.Equal(t, float64(45), task["duration"])
+	for _, v := range []map[string]any{
+		{"title": "Без времени", "duration": 30},
+		{"title": "Неверное время", "time": "25:00"},
+		{"title": "Слишком долго", "time": "10:00", "duration": 7*24*60 + 1},
+		{"title": "Неизвестный пояс", "timezone": "Mars/Olympus"},
+	} {
+		code, body := tokenJSON(t, "api/task", token, v, http.MethodPost)
+		assert.Equal(t, http.StatusBadRequest, code, string(body))
+	}
+	task["time"] = "7:05"
+	code, body = tokenJSON(t, "api/task", token, task, http.MethodPut)
+	assert.Equal(t, http.StatusOK, code, string(body))
+	assert.Equal(t, "07:05", getTokenTask(t, token, id)["time"])
+
+	// изменение без времени, длительности и часового пояса их не сбрасывает, пустое время сбрасывает и длительность
+	update := func(values map[string]any) map[string]any {
+		values["id"], values["date"], values["title"], values["comment"], values["repeat"] = id, task["date"], "Созвон", "", ""
+		code, body := tokenJSON(t, "api/task", token, values, http.MethodPut)
+		assert.Equal(t, http.StatusOK, code, string(body))
+		return getTokenTask(t, token, id)
+	}
+	task = update(map[string]any{"timezone": "Pacific/Kiritimati"})
+	assert.Equal(t, "07:05", task["time"])
+	assert.Equal(t, float64(45), task["duration"])
+	assert.Equal(t, "Pacific/Kiritimati", task["timezone"])
+	task = update(map[string]any{})
+	assert.Equal(t, "07:05", task["time"])
+	assert.Equal(t, float64(45), task["duration"])
+	assert.Equal(t, "Pacific/Kiritimati", task["timezone"])
+	task = update(map[string]any{"time": ""})
+	assert.Nil(t, task["time"])
+	assert.Nil(t, task["duration"])
+	assert.Equal(t, "Pacific/Kiritimati", task["timezone"])
+
+	// следующая дата после выполнения считается от сегодняшнего дня в часовом поясе задачи
+	id = addTokenTask(t, token, map[string]any{"title": "Ежедневная", "date": today(west, 0), "repeat": "d 1",
+		"timezone": "Pacific/Pago_Pago"})
+	code, body = tokenJSON(t, "api/task/done?id="+id, token, nil, http.MethodPost)
+	assert.Equal(t, http.StatusOK, code, string(body))
+	assert.Equal(t, today(west, 1), getTokenTask(t, token, id)["date"])
+	code, body = tokenJSON(t, "api/task?id="+id, token, nil, http.MethodDelete)
+	assert.Equal(t, http.StatusOK, code, string(body))
+
+	code, body = tokenJSON(t, "api/nextdate?date=20240101&repeat="+url.QueryEscape("d 1"), token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code, string(body))
+	assert.Equal(t, today(east, 1), string(body))
+
+	// в повестке задачи на весь день идут первыми, остальные - по времени начала
+	day := today(east, 3)
+	for _, v := range []map[string]any{
+		{"title": "Вечер", "date": day, "time": "18:00"},
+		{"title": "Весь день", "date": day},
+		{"title": "Утро", "date": day, "time": "08:00", "duration": 30},
+	} {
+		addTokenTask(t, token, v)
+	}
+	code, body = tokenJSON(t, "api/agenda?from="+day+"&to="+day, token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code, string(body))
+	var agenda struct {
+		Days []struct {
+			Tasks []map[string]any `json:"tasks"`
+		} `json:"days"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &agenda))
+	var titles []any
+	for _, d := range agenda.Days {
+		for _, task := range d.Tasks {
+			titles = append(titles, task["title"])
+		}
+	}
+	assert.Equal(t, []any{"Весь день", "Утро", "Вечер"}, titles)
+}
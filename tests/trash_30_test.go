@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eOne007/final-project-yapr/pkg/api"
+	"github.com/eOne007/final-project-yapr/pkg/db"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+)
+
+func TestTrash(t *testing.T) {
+	token := signup(t, fmt.Sprintf("trash%d", time.Now().UnixNano()), "trash-password")
+	day := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+
+	trash := func() []string {
+		code, m := tokenMap(t, "api/trash", token, nil, http.MethodGet)
+		assert.Equal(t, http.StatusOK, code, m)
+		var ids []string
+		for _, v := range m["tasks"].([]any) {
+			task := v.(map[string]any)
+			_, err := time.Parse(time.RFC3339, fmt.Sprint(task["deleted_at"]))
+			assert.NoError(t, err)
+			ids = append(ids, fmt.Sprint(task["id"]))
+		}
+		return ids
+	}
+	total := func(query string) any {
+		_, m := tokenMap(t, "api/tasks?"+query, token, nil, http.MethodGet)
+		return m["total"]
+	}
+
+	first := addTokenTask(t, token, map[string]any{"date": day, "title": "Отнести книги в библиотеку", "repeat": "d 2"})
+	second := addTokenTask(t, token, map[string]any{"date": day, "title": "Продлить абонемент", "repeat": "d 2"})
+	assert.Empty(t, trash())
+
+	// удаленная задача попадает в корзину и не видна в списке, поиске и при запросе по id
+	code, _ := tokenMap(t, "api/task?id="+first, token, nil, http.MethodDelete)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = tokenMap(t, "api/task?id="+first, token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, float64(1), total(""))
+	assert.Equal(t, float64(0), total("search="+url.QueryEscape("библиотеку")))
+	assert.Equal(t, []string{first}, trash())
+
+	// задачу в корзине нельзя изменить, выполнить или удалить еще раз
+	code, _ = tokenMap(t, "api/task", token, map[string]any{"id": first, "date": day, "title": "Изменить"}, http.MethodPut)
+	assert.NotEqual(t, http.StatusOK, code)
+	code, _ = tokenMap(t, "api/task/done?id="+first, token, nil, http.MethodPost)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = tokenMap(t, "api/task?id="+first, token, nil, http.MethodDelete)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// удаленная задача остается в таблице с отметкой времени удаления
+	conn := openDB(t)
+	defer conn.Close()
+	var deletedAt string
+	assert.NoError(t, conn.Get(&deletedAt, conn.Rebind(`SELECT deleted_at FROM scheduler WHERE id = ?`), first))
+	assert.NotEmpty(t, deletedAt)
+
+	// восстановление возвращает задачу в список
+	code, restored := tokenMap(t, "api/trash/restore?id="+first, token, nil, http.MethodPost)
+	assert.Equal(t, http.StatusOK, code, restored)
+	assert.Equal(t, first, restored["id"])
+	assert.Equal(t, "Отнести книги в библиотеку", restored["title"])
+	assert.Empty(t, trash())
+	assert.Equal(t, float64(1), total("search="+url.QueryEscape("библиотеку")))
+	code, _ = tokenMap(t, "api/trash/restore?id="+first, token, nil, http.MethodPost)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// сначала идут задачи, удаленные последними
+	tokenMap(t, "api/task?id="+first, token, nil, http.MethodDelete)
+	time.Sleep(1100 * time.Millisecond)
+	code, m := tokenMap(t, "api/task?id="+second, token, nil, http.MethodDelete)
+	assert.Equal(t, http.StatusOK, code)
+	undo, _ := m["undo_token"].(string)
+	assert.Equal(t, []string{second, first}, trash())
+
+	// корзина другого пользователя не видна
+	other := signup(t, fmt.Sprintf("trash_other%d", time.Now().UnixNano()), "trash-password")
+	code, body := tokenJSON(t, "api/trash", other, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `[]`, string(mustField(t, body, "tasks")))
+	code, _ = tokenJSON(t, "api/trash/restore?id="+first, other, nil, http.MethodPost)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// очистка корзины удаляет задачи окончательно, отмена удаления по-прежнему работает
+	code, m = tokenMap(t, "api/trash", token, nil, http.MethodDelete)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(2), m["deleted"])
+	assert.Empty(t, trash())
+	code, _ = tokenMap(t, "api/trash/restore?id="+first, token, nil, http.MethodPost)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, m = tokenMap(t, "api/undo?token="+undo, token, nil, http.MethodPost)
+	assert.Equal(t, http.StatusOK, code, m)
+	assert.Equal(t, second, m["id"])
+	assert.Equal(t, float64(1), total(""))
+
+	code, _ = tokenMap(t, "api/trash/restore", token, nil, http.MethodPost)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = tokenMap(t, "api/trash/restore?id="+first, token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+	code, _ = tokenMap(t, "api/trash", token, nil, http.MethodPost)
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+}
+
+func TestPurgeTrash(t *testing.T) {
+	dbfile := filepath.Join(t.TempDir(), "trash.db")
+	store, err := db.NewSQLiteStore(dbfile)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	var ids []string
+	for _, title := range []string{"Старая", "Свежая", "Не удалена"} {
+		id, err := store.Add(0, &db.Task{Date: "20240101", Title: title})
+		assert.NoError(t, err)
+		ids = append(ids, fmt.Sprint(id))
+	}
+	assert.NoError(t, store.Delete(0, ids[0], nil))
+	assert.NoError(t, store.Delete(0, ids[1], nil))
+
+	conn, err := sqlx.Connect("sqlite", dbfile)
+	assert.NoError(t, err)
+	defer conn.Close()
+	old := time.Now().Add(-31 * 24 * time.Hour).UTC().Format(db.TimestampFormat)
+	_, err = conn.Exec(`UPDATE scheduler SET deleted_at = ? WHERE id = ?`, old, ids[0])
+	assert.NoError(t, err)
+
+	// при отмененном контексте выполняется одна очистка
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	api.PurgeTrash(ctx, store, api.TrashRetention, time.Minute)
+
+	var left []string
+	assert.NoError(t, conn.Select(&left, `SELECT title FROM scheduler ORDER BY id`))
+	assert.Equal(t, []string{"Свежая", "Не удалена"}, left)
+	trash, err := store.Trash(0)
+	assert.NoError(t, err)
+	if assert.Len(t, trash, 1) {
+		assert.Equal(t, ids[1], trash[0].ID)
+	}
+}
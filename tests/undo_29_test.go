@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUndo(t *testing.T) {
+	token := signup(t, fmt.Sprintf("undo%d", time.Now().UnixNano()), "undo-password")
+	day := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+
+	undoToken := func(code int, m map[string]any) string {
+		assert.Equal(t, http.StatusOK, code, m)
+		token, _ := m["undo_token"].(string)
+		assert.NotEmpty(t, token)
+		expires, err := time.Parse(time.RFC3339, fmt.Sprint(m["undo_expires_at"]))
+		assert.NoError(t, err)
+		assert.True(t, expires.After(time.Now()))
+		return token
+	}
+	history := func(id string) []any {
+		_, m := tokenMap(t, "api/task/history?id="+id, token, nil, http.MethodGet)
+		list, _ := m["completions"].([]any)
+		return list
+	}
+
+	// удаленная задача восстанавливается с прежним id и всеми полями, в том числе в поиске
+	id := addTokenTask(t, token, map[string]any{"date": day, "title": "Купить билеты", "comment": "на поезд",
+		"time": "10:15", "duration": 20})
+	before := getTokenTask(t, token, id)
+	undo := undoToken(tokenMap(t, "api/task?id="+id, token, nil, http.MethodDelete))
+	code, _ := tokenMap(t, "api/task?id="+id, token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, restored := tokenMap(t, "api/undo?token="+undo, token, nil, http.MethodPost)
+	assert.Equal(t, http.StatusOK, code, restored)
+	assert.Equal(t, id, restored["id"])
+	code, after := tokenMap(t, "api/task?id="+id, token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, before, after)
+	_, found := tokenMap(t, "api/tasks?search="+url.QueryEscape("билеты"), token, nil, http.MethodGet)
+	assert.Equal(t, float64(1), found["total"])
+
+	// токен действует один раз
+	code, _ = tokenMap(t, "api/undo?token="+undo, token, nil, http.MethodPost)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// отмена выполнения повторяющейся задачи возвращает дату и число выполнений и удаляет отметку из журнала
+	id = addTokenTask(t, token, map[string]any{"date": day, "title": "Зарядка", "repeat": "d 3", "remaining": 5})
+	undo = undoToken(tokenMap(t, "api/task/done?id="+id, token, nil, http.MethodPost))
+	moved := getTokenTask(t, token, id)
+	assert.NotEqual(t, day, moved["date"])
+	assert.Equal(t, float64(4), moved["remaining"])
+	assert.Len(t, history(id), 1)
+
+	code, _ = tokenMap(t, "api/undo?token="+undo, token, nil, http.MethodPost)
+	assert.Equal(t, http.StatusOK, code)
+	task := getTokenTask(t, token, id)
+	assert.Equal(t, day, task["date"])
+	assert.Equal(t, float64(5), task["remaining"])
+	assert.Empty(t, history(id))
+
+	// выполненная разовая задача удаляется и восстанавливается
+	id = addTokenTask(t, token, map[string]any{"date": day, "title": "Позвонить"})
+	undo = undoToken(tokenMap(t, "api/task/done?id="+id, token, nil, http.MethodPost))
+	code, _ = tokenMap(t, "api/task?id="+id, token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = tokenMap(t, "api/undo?token="+undo, token, nil, http.MethodPost)
+	assert.Equal(t, http.StatusOK, code)
+	task = getTokenTask(t, token, id)
+	assert.Equal(t, "Позвонить", task["title"])
+	assert.Empty(t, history(id))
+
+	// токен действует только для своего пользователя
+	undo = undoToken(tokenMap(t, "api/task?id="+id, token, nil, http.MethodDelete))
+	other := signup(t, fmt.Sprintf("undo_other%d", time.Now().UnixNano()), "undo-password")
+	code, _ = tokenJSON(t, "api/undo?token="+undo, other, nil, http.MethodPost)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _ = tokenMap(t, "api/undo?token=unknown", token, nil, http.MethodPost)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = tokenMap(t, "api/undo", token, nil, http.MethodPost)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = tokenMap(t, "api/undo?token="+undo, token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+}
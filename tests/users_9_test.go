@@ -0,0 +1,228 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eOne007/final-project-yapr/pkg/api"
+	"github.com/eOne007/final-project-yapr/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func tokenJSON(t *testing.T, apipath, token string, values map[string]any, method string) (int, []byte) {
+	var data []byte
+	if len(values) > 0 {
+		var err error
+		data, err = json.Marshal(values)
+		assert.NoError(t, err)
+	}
+	req, err := http.NewRequest(method, getURL(apipath), bytes.NewBuffer(data))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return resp.StatusCode, body
+}
+
+// tokenMap выполняет запрос с токеном пользователя и разбирает JSON-ответ
+func tokenMap(t *testing.T, apipath, token string, values map[string]any, method string) (int, map[string]any) {
+	code, body := tokenJSON(t, apipath, token, values, method)
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m), string(body))
+	return code, m
+}
+
+// addTokenTask добавляет задачу пользователя и возвращает ее идентификатор
+func addTokenTask(t *testing.T, token string, values map[string]any) string {
+	code, m := tokenMap(t, "api/task", token, values, http.MethodPost)
+	assert.Equal(t, http.StatusCreated, code, m)
+	id, _ := m["id"].(string)
+	return id
+}
+
+// getTokenTask возвращает задачу пользователя по идентификатору
+func getTokenTask(t *testing.T, token, id string) map[string]any {
+	code, m := tokenMap(t, "api/task?id="+id, token, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code, m)
+	return m
+}
+
+// signup регистрирует пользователя; при заданном TODO_PASSWORD регистрация
+// закрыта, поэтому запрос отправляется с токеном владельца Token
+func signup(t *testing.T, login, password string) string {
+	code, body := tokenJSON(t, "api/signup", Token, map[string]any{
+		"login":    login,
+		"password": password,
+	}, http.MethodPost)
+	assert.Equal(t, http.StatusCreated, code, string(body))
+
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	token, _ := m["token"].(string)
+	assert.NotEmpty(t, token)
+	return token
+}
+
+func TestUsers(t *testing.T) {
+	suffix := time.Now().UnixNano()
+	alice := signup(t, fmt.Sprintf("alice%d", suffix), "alice-password")
+	bob := signup(t, fmt.Sprintf("bob%d", suffix), "bob-password")
+
+	code, _ := tokenJSON(t, "api/signup", Token, map[string]any{
+		"login":    fmt.Sprintf("alice%d", suffix),
+		"password": "other",
+	}, http.MethodPost)
+	assert.Equal(t, http.StatusConflict, code)
+
+	code, wrong := tokenJSON(t, "api/signin", "", map[string]any{
+		"login":    fmt.Sprintf("alice%d", suffix),
+		"password": "wrong",
+	}, http.MethodPost)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// ответ на вход по неизвестному логину не отличается от ответа на неверный пароль
+	code, unknown := tokenJSON(t, "api/signin", "", map[string]any{
+		"login":    fmt.Sprintf("nobody%d", suffix),
+		"password": "wrong",
+	}, http.MethodPost)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, string(wrong), string(unknown))
+
+	code, body := tokenJSON(t, "api/signin", "", map[string]any{
+		"login":    fmt.Sprintf("alice%d", suffix),
+		"password": "alice-password",
+	}, http.MethodPost)
+	assert.Equal(t, http.StatusOK, code)
+	var signed map[string]any
+	assert.NoError(t, json.Unmarshal(body, &signed))
+	assert.NotEmpty(t, signed["token"])
+
+	code, body = tokenJSON(t, "api/task", alice, map[string]any{
+		"title":   "Задача Алисы",
+		"comment": "секрет",
+	}, http.MethodPost)
+	assert.Equal(t, http.StatusCreated, code)
+	var created map[string]any
+	assert.NoError(t, json.Unmarshal(body, &created))
+	id := fmt.Sprint(created["id"])
+
+	code, _ = tokenJSON(t, "api/task?id="+id, alice, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _ = tokenJSON(t, "api/task?id="+id, bob, nil, http.MethodGet)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = tokenJSON(t, "api/task/done?id="+id, bob, nil, http.MethodPost)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = tokenJSON(t, "api/task?id="+id, bob, nil, http.MethodDelete)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = tokenJSON(t, "api/task", bob, map[string]any{
+		"id":    id,
+		"date":  time.Now().Format(`20060102`),
+		"title": "Чужая задача",
+	}, http.MethodPut)
+	assert.NotEqual(t, http.StatusOK, code)
+
+	code, body = tokenJSON(t, "api/tasks", bob, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code)
+	var list struct {
+		Tasks []map[string]string `json:"tasks"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &list))
+	assert.Empty(t, list.Tasks)
+
+	code, body = tokenJSON(t, "api/tasks?search=секрет", alice, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code)
+	assert.NoError(t, json.Unmarshal(body, &list))
+	assert.Len(t, list.Tasks, 1)
+
+	code, _ = tokenJSON(t, "api/task?id="+id, alice, nil, http.MethodDelete)
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestSignupClosed(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "secret123")
+	t.Setenv("TODO_SIGNUP", "")
+	srv := httptest.NewServer(api.NewServer(db.NewMemoryStore()))
+	defer srv.Close()
+
+	account := func(login string) map[string]any {
+		return map[string]any{"login": login, "password": "p"}
+	}
+
+	// при заданном пароле регистрация по умолчанию закрыта
+	code, m := serverJSON(t, srv, "api/signup", account("x1"), http.MethodPost)
+	assert.Equal(t, http.StatusForbidden, code, m)
+	assert.NotContains(t, m, "token")
+
+	// владелец пароля может зарегистрировать пользователя, сам пользователь - нет
+	code, m = serverJSON(t, srv, "api/signin", map[string]any{"password": "secret123"}, http.MethodPost)
+	assert.Equal(t, http.StatusOK, code, m)
+	owner, _ := m["token"].(string)
+	code, m = serverJSON(t, srv, "api/signup", account("x1"), http.MethodPost, owner)
+	assert.Equal(t, http.StatusCreated, code, m)
+	user, _ := m["token"].(string)
+	code, _ = serverJSON(t, srv, "api/signup", account("x2"), http.MethodPost, user)
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// регистрацию можно явно открыть или закрыть
+	t.Setenv("TODO_SIGNUP", "on")
+	code, _ = serverJSON(t, srv, "api/signup", account("x3"), http.MethodPost)
+	assert.Equal(t, http.StatusCreated, code)
+	t.Setenv("TODO_PASSWORD", "")
+	t.Setenv("TODO_SIGNUP", "off")
+	code, _ = serverJSON(t, srv, "api/signup", account("x4"), http.MethodPost)
+	assert.Equal(t, http.StatusForbidden, code)
+}
+
+func TestSecretKeyPersisted(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "")
+	t.Setenv("TODO_SIGNUP", "")
+	t.Setenv("TODO_SECRET", "")
+	dbfile := filepath.Join(t.TempDir(), "keys.db")
+
+	start := func() (*httptest.Server, func()) {
+		store, err := db.NewSQLiteStore(dbfile)
+		assert.NoError(t, err)
+		srv := httptest.NewServer(api.NewServer(store))
+		return srv, func() {
+			srv.Close()
+			store.Close()
+		}
+	}
+
+	srv, stop := start()
+	code, m := serverJSON(t, srv, "api/signup", map[string]any{"login": "restart", "password": "p"}, http.MethodPost)
+	assert.Equal(t, http.StatusCreated, code, m)
+	token, _ := m["token"].(string)
+	stop()
+
+	// ключ подписи сохраняется в БД, поэтому токен действителен после перезапуска сервера
+	srv, stop = start()
+	defer stop()
+	code, m = serverJSON(t, srv, "api/tasks", nil, http.MethodGet, token)
+	assert.Equal(t, http.StatusOK, code, m)
+
+	// у сервера с другой БД свой ключ
+	other := httptest.NewServer(api.NewServer(db.NewMemoryStore()))
+	defer other.Close()
+	code, _ = serverJSON(t, other, "api/tasks", nil, http.MethodGet, token)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// ключ из TODO_SECRET заменяет сохраненный
+	t.Setenv("TODO_SECRET", "another-signing-key")
+	code, _ = serverJSON(t, srv, "api/tasks", nil, http.MethodGet, token)
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
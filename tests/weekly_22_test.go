@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDateWeeklyInterval(t *testing.T) {
+	if !FullNextDate {
+		return
+	}
+	// now = 26.01.2024, пятница
+	tbl := []nextDate{
+		{"20240115", "w 1,4 /2", "20240129"},
+		{"20240118", "w 1,4 /2", "20240129"},
+		{"20240122", "w 1,4 /2", "20240205"},
+		{"20240108", "w 5 /3", "20240202"},
+		{"20240101", "w 7 /2", "20240204"},
+		{"20240101", "w 1 /1", "20240129"},
+		{"20230102", "w 1 /52", "20241230"},
+		{"20240115", "w 1 /0", ""},
+		{"20240115", "w 1 /53", ""},
+		{"20240115", "w 8 /2", ""},
+		{"20240115", "w 1 2", ""},
+	}
+	for _, v := range tbl {
+		urlPath := fmt.Sprintf("api/nextdate?now=20240126&date=%s&repeat=%s",
+			url.QueryEscape(v.date), url.QueryEscape(v.repeat))
+		get, err := getBody(urlPath)
+		assert.NoError(t, err)
+		next := strings.TrimSpace(string(get))
+		_, err = time.Parse("20060102", next)
+		if err != nil && len(v.want) == 0 {
+			continue
+		}
+		assert.Equal(t, v.want, next, `{%q, %q, %q}`,
+			v.date, v.repeat, v.want)
+	}
+}
+
+// TestDoneWeeklyInterval проверяет, что последовательные отметки о выполнении
+// сохраняют отсчет недель от недели начальной даты задачи
+func TestDoneWeeklyInterval(t *testing.T) {
+	now := time.Now()
+	// ближайший понедельник после сегодняшнего дня
+	monday := now.AddDate(0, 0, 7-int(now.Weekday()+6)%7)
+	id := addTask(t, task{
+		date:   monday.Format(`20060102`),
+		title:  "Спринт-ревью",
+		repeat: "w 1,4 /2",
+	})
+
+	for _, days := range []int{3, 14, 17, 28} {
+		ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotContains(t, ret, "error")
+		assert.NotEmpty(t, ret["undo_token"])
+
+		body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+		assert.NoError(t, err)
+		var task map[string]string
+		assert.NoError(t, json.Unmarshal(body, &task))
+		assert.Equal(t, monday.AddDate(0, 0, days).Format(`20060102`), task["date"])
+	}
+
+	ret, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.NotContains(t, ret, "error")
+	assert.NotEmpty(t, ret["undo_token"])
+}